@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/azuredevops"
+	"github.com/k8scat/mirror-git-go/pkg/bitbucket"
+	"github.com/k8scat/mirror-git-go/pkg/config"
+	"github.com/k8scat/mirror-git-go/pkg/e_gitee_v8"
+	"github.com/k8scat/mirror-git-go/pkg/filter"
+	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/gitea"
+	"github.com/k8scat/mirror-git-go/pkg/gitee"
+	"github.com/k8scat/mirror-git-go/pkg/github"
+	"github.com/k8scat/mirror-git-go/pkg/gitlab"
+	"github.com/k8scat/mirror-git-go/pkg/local"
+	"github.com/k8scat/mirror-git-go/pkg/mapping"
+	"github.com/k8scat/mirror-git-go/pkg/report"
+	"github.com/k8scat/mirror-git-go/pkg/schedule"
+	"github.com/k8scat/mirror-git-go/pkg/state"
+	"github.com/k8scat/mirror-git-go/pkg/types"
+	"github.com/k8scat/mirror-git-go/pkg/urllist"
+)
+
+// job mirrors a source provider to one or more target providers
+type job struct {
+	// name is empty for the job given by the -source and -target flags
+	name        string
+	sourceName  string
+	source      types.SourceGit
+	targets     []jobTarget
+	mapper      *mapping.Mapper
+	filter      *filter.Filter
+	concurrency int
+	schedule    *schedule.Schedule
+}
+
+type jobTarget struct {
+	name string
+	git  types.TargetGit
+}
+
+// jobRun holds the settings of a single run of a job that the mirror and the outputs it writes use
+type jobRun struct {
+	// name is the name of the job, empty for a run from the -source and -target flags
+	name string
+	// source and target name the providers in the report and the run state
+	source      string
+	target      string
+	filter      *filter.Filter
+	concurrency int
+	state       *state.Store
+}
+
+// outputPath inserts the name of the run into the path of a report file
+func (r *jobRun) outputPath(path string) string {
+	if r.name == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + r.name + ext
+}
+
+// targetNames returns the provider names of the targets of the job
+func (j *job) targetNames() []string {
+	names := make([]string, len(j.targets))
+	for i, t := range j.targets {
+		names[i] = t.name
+	}
+	return names
+}
+
+// hasLocalTarget reports whether the repos are cloned into the work dir instead of pushed
+func hasLocalTarget(targets []jobTarget) bool {
+	return slices.ContainsFunc(targets, func(t jobTarget) bool { return t.git.Name() == git.Local })
+}
+
+// buildJobs creates the jobs of the config, or a single job from the -source and -target flags
+// if the config has none. With -job only the named jobs are returned.
+func buildJobs(ctx context.Context, cfg *config.Config) ([]*job, error) {
+	jobConfigs := cfg.Jobs
+	if len(jobConfigs) == 0 {
+		if len(jobNames) > 0 {
+			return nil, fmt.Errorf("-job requires a config file with jobs")
+		}
+		jobConfigs = []config.Job{{Source: sourceType, Targets: []string{targetType}, Schedule: scheduleSpec}}
+	}
+	for _, name := range jobNames {
+		if !slices.ContainsFunc(jobConfigs, func(jc config.Job) bool { return jc.Name == name }) {
+			return nil, fmt.Errorf("job %q not found in the config", name)
+		}
+	}
+
+	sources := make(map[string]types.SourceGit)
+	targets := make(map[string]types.TargetGit)
+	var jobs []*job
+	for _, jc := range jobConfigs {
+		if len(jobNames) > 0 && !slices.Contains(jobNames, jc.Name) {
+			continue
+		}
+
+		j := &job{name: jc.Name, sourceName: jc.Source, concurrency: jc.Concurrency}
+		if j.concurrency == 0 {
+			j.concurrency = concurrency
+		}
+
+		var err error
+		if j.source = sources[jc.Source]; j.source == nil {
+			if j.source, err = newSource(ctx, cfg, jc.Source); err != nil {
+				return nil, err
+			}
+			sources[jc.Source] = j.source
+		}
+		for _, name := range jc.Targets {
+			t := targets[name]
+			if t == nil {
+				if t, err = newTarget(ctx, cfg, name); err != nil {
+					return nil, err
+				}
+				targets[name] = t
+			}
+			j.targets = append(j.targets, jobTarget{name: name, git: t})
+		}
+
+		filterConfig := cfg.Filters
+		if jc.Filters != nil {
+			filterConfig = *jc.Filters
+		}
+		if j.filter, err = filter.New(mergeFilterOptions(filterConfig, filterOpts)); err != nil {
+			return nil, fmt.Errorf("invalid filters: %w", err)
+		}
+
+		mode := jc.Mapping.Mode
+		if mode == "" {
+			mode = namespaceMode
+		}
+		if j.mapper, err = mapping.NewMapper(mode); err != nil {
+			return nil, err
+		}
+		if jc.Mapping.Separator != "" {
+			j.mapper.Separator = jc.Mapping.Separator
+		}
+		for _, t := range j.targets {
+			if err := j.mapper.Validate(t.git); err != nil {
+				return nil, fmt.Errorf("invalid namespace mode for target %s: %w", t.name, err)
+			}
+		}
+
+		if jc.Schedule != "" {
+			if j.schedule, err = schedule.Parse(jc.Schedule); err != nil {
+				return nil, err
+			}
+		} else if daemon {
+			if jc.Name == "" {
+				return nil, fmt.Errorf("-daemon requires -schedule")
+			}
+			return nil, fmt.Errorf("job %s has no schedule, required by -daemon", jc.Name)
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// newSource creates the source provider of the given name from the environment and the config
+func newSource(ctx context.Context, cfg *config.Config, name string) (types.SourceGit, error) {
+	var sourceGit types.SourceGit
+	switch typ := cfg.ProviderType(name); typ {
+	case git.EGiteeV8:
+		sourceGit = e_gitee_v8.NewEnterpriseGiteeV8FromEnv()
+	case git.GitHub:
+		sourceGit = github.NewGitHubFromEnv()
+	case git.GitLab:
+		sourceGit = gitlab.NewGitLabFromEnv()
+	case git.Gitee:
+		sourceGit = gitee.NewGiteeFromEnv()
+	case git.Gitea:
+		sourceGit = gitea.NewGiteaFromEnv()
+	case git.Bitbucket:
+		sourceGit = bitbucket.NewBitbucketFromEnv()
+	case git.AzureDevOps:
+		sourceGit = azuredevops.NewAzureDevOpsFromEnv()
+	case git.URLList:
+		sourceGit = urllist.NewURLListFromEnv()
+	case git.Local:
+		sourceGit = local.NewLocalFromEnv()
+	default:
+		return nil, fmt.Errorf("invalid source type %q of provider %s", typ, name)
+	}
+	p := cfg.Providers[name]
+	applyProviderConfig(sourceGit, p)
+	if err := resolveCredentials(ctx, sourceGit, p.Credentials); err != nil {
+		return nil, fmt.Errorf("resolve credentials of provider %s failed: %w", name, err)
+	}
+	limitRate(sourceGit, p)
+	return sourceGit, nil
+}
+
+// newTarget creates the target provider of the given name from the environment and the config
+func newTarget(ctx context.Context, cfg *config.Config, name string) (types.TargetGit, error) {
+	var targetGit types.TargetGit
+	switch typ := cfg.ProviderType(name); typ {
+	case git.GitLab:
+		targetGit = gitlab.NewGitLabFromEnv()
+	case git.GitHub:
+		targetGit = github.NewGitHubFromEnv()
+	case git.Local:
+		targetGit = &local.Local{}
+	case git.Gitee:
+		targetGit = gitee.NewGiteeFromEnv()
+	case git.Gitea:
+		targetGit = gitea.NewGiteaFromEnv()
+	case git.Bitbucket:
+		targetGit = bitbucket.NewBitbucketFromEnv()
+	case git.AzureDevOps:
+		targetGit = azuredevops.NewAzureDevOpsFromEnv()
+	default:
+		return nil, fmt.Errorf("invalid target type %q of provider %s", typ, name)
+	}
+	p := cfg.Providers[name]
+	applyProviderConfig(targetGit, p)
+	if err := resolveCredentials(ctx, targetGit, p.Credentials); err != nil {
+		return nil, fmt.Errorf("resolve credentials of provider %s failed: %w", name, err)
+	}
+	limitRate(targetGit, p)
+	return targetGit, nil
+}
+
+// runJobs runs every job once, in order
+func runJobs(ctx, stopping context.Context, jobs []*job) error {
+	var errs []error
+	for _, j := range jobs {
+		if stopping.Err() != nil {
+			break
+		}
+		if err := runJob(ctx, stopping, j); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runDaemon runs the jobs at their schedules until stopping is done
+func runDaemon(ctx, stopping context.Context, jobs []*job) error {
+	next := make([]time.Time, len(jobs))
+	for i, j := range jobs {
+		next[i] = j.schedule.Next(time.Now())
+	}
+
+	for {
+		i := 0
+		for k := range jobs {
+			if next[i].IsZero() || (!next[k].IsZero() && next[k].Before(next[i])) {
+				i = k
+			}
+		}
+		if next[i].IsZero() {
+			return fmt.Errorf("no job is scheduled to run again")
+		}
+
+		slog.Info("waiting for next scheduled job", "job", jobs[i].name, "at", next[i].Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next[i]))
+		select {
+		case <-stopping.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		if err := runJob(ctx, stopping, jobs[i]); err != nil {
+			slog.Error("scheduled job failed", "job", jobs[i].name, "error", err)
+		}
+		next[i] = jobs[i].schedule.Next(time.Now())
+	}
+}
+
+// runJob mirrors the source of a job to all of its targets, cloning each repo once
+func runJob(ctx, stopping context.Context, j *job) error {
+	if j.name != "" {
+		slog.Info("run job", "job", j.name, "source", j.sourceName, "targets", j.targetNames())
+	}
+
+	run := &jobRun{
+		name:        j.name,
+		source:      j.sourceName,
+		target:      strings.Join(j.targetNames(), ","),
+		filter:      j.filter,
+		concurrency: j.concurrency,
+	}
+	var err error
+	if dryRun {
+		err = planJob(ctx, run, j)
+	} else {
+		err = runMirrorJob(ctx, stopping, run, j)
+	}
+	if err != nil {
+		slog.Error("mirror failed", "error", err, "job", j.name, "source", j.sourceName, "targets", j.targetNames())
+	}
+	return err
+}
+
+// planJob prints what mirroring the job would do on each of its targets
+func planJob(ctx context.Context, run *jobRun, j *job) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, t := range j.targets {
+		targetRun := *run
+		targetRun.target = t.name
+		p, err := planMirror(ctx, &targetRun, j.source, t.git, j.mapper)
+		if err != nil {
+			return fmt.Errorf("plan failed: %w", err)
+		}
+		p.Print(os.Stdout)
+		if len(p.Collisions) > 0 || len(p.Errors) > 0 {
+			errs = append(errs, fmt.Errorf("plan for %s has %d collisions and %d errors", t.name, len(p.Collisions), len(p.Errors)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runMirrorJob mirrors the source of the job to its targets in a clone dir of its own
+func runMirrorJob(ctx, stopping context.Context, run *jobRun, j *job) error {
+	var err error
+	run.state, err = state.Open(filepath.Join(workDir, "state_"+j.sourceName+"_"+strings.Join(j.targetNames(), "_")+".json"))
+	if err != nil {
+		return fmt.Errorf("open run state failed: %w", err)
+	}
+
+	cloneDir := filepath.Join(workDir, "repos_"+time.Now().Format("20060102150405"))
+	if run.name != "" {
+		cloneDir += "_" + run.name
+	}
+	if err := os.MkdirAll(cloneDir, 0755); err != nil {
+		return fmt.Errorf("create clone dir failed: %w", err)
+	}
+	slog.Info("clone dir created", "dir", cloneDir)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	err = runMirror(ctx, stopping, run, cloneDir, j.source, j.targets, j.mapper)
+
+	if keepWorkDir {
+		slog.Info("keeping clone directory", "dir", cloneDir)
+	} else if !hasLocalTarget(j.targets) {
+		slog.Info("cleaning up clone directory", "dir", cloneDir)
+		if err := os.RemoveAll(cloneDir); err != nil {
+			slog.Error("remove clone dir failed", "error", err, "clone_dir", cloneDir)
+		}
+	}
+	return err
+}
+
+// exitCode picks the most severe exit code of the errors of all runs
+func exitCode(err error) int {
+	code := 1
+	var mirrorErr *report.MirrorError
+	if errors.As(err, &mirrorErr) {
+		code = mirrorErr.ExitCode()
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			code = max(code, exitCode(e))
+		}
+	}
+	return code
+}
@@ -1,214 +1,1022 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/k8scat/mirror-git-go/pkg/azuredevops"
+	"github.com/k8scat/mirror-git-go/pkg/bitbucket"
+	"github.com/k8scat/mirror-git-go/pkg/cache"
+	"github.com/k8scat/mirror-git-go/pkg/config"
+	"github.com/k8scat/mirror-git-go/pkg/credential"
 	"github.com/k8scat/mirror-git-go/pkg/e_gitee_v8"
+	"github.com/k8scat/mirror-git-go/pkg/filter"
 	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/gitea"
 	"github.com/k8scat/mirror-git-go/pkg/gitee"
 	"github.com/k8scat/mirror-git-go/pkg/github"
 	"github.com/k8scat/mirror-git-go/pkg/gitlab"
 	"github.com/k8scat/mirror-git-go/pkg/local"
+	"github.com/k8scat/mirror-git-go/pkg/mapping"
+	"github.com/k8scat/mirror-git-go/pkg/plan"
+	"github.com/k8scat/mirror-git-go/pkg/ratelimit"
+	"github.com/k8scat/mirror-git-go/pkg/redact"
+	"github.com/k8scat/mirror-git-go/pkg/report"
+	"github.com/k8scat/mirror-git-go/pkg/retry"
 	"github.com/k8scat/mirror-git-go/pkg/types"
+	"github.com/k8scat/mirror-git-go/pkg/urllist"
 )
 
 var (
-	sourceType string
-	targetType string
-	timeout    int
+	sourceType    string
+	targetType    string
+	timeout       int
+	configFile    string
+	namespaceMode string
+	cacheDir      string
+	workDir       string
+	resume        bool
+	reportFile    string
+	junitFile     string
+	summaryFile   string
+	retryFrom     string
+	maxRetries    int
+	rps           float64
+	repoTimeout   int
+	phaseTimeout  int
+	gracePeriod   int
+	keepWorkDir   bool
+	dryRun        bool
+	filterOpts    filter.Options
+	concurrency   int
+	jobNames      []string
+	daemon        bool
+	scheduleSpec  string
+
+	mirrorCache *cache.Cache
+	retryPolicy = retry.DefaultPolicy
 )
 
 func main() {
 	flag.IntVar(&timeout, "timeout", 3600, "timeout in seconds")
-	flag.StringVar(&sourceType, "source", git.EGiteeV8, "source git service")
-	flag.StringVar(&targetType, "target", git.GitHub, "target git service")
+	flag.IntVar(&repoTimeout, "repo-timeout", 0, "timeout in seconds for mirroring a single repo, 0 for no limit")
+	flag.IntVar(&phaseTimeout, "phase-timeout", 0, "timeout in seconds for a single clone, API check, create or push of a repo including its retries, 0 for no limit")
+	flag.StringVar(&sourceType, "source", git.EGiteeV8, "source git service, or the name of a provider of the config file")
+	flag.StringVar(&targetType, "target", git.GitHub, "target git service, or the name of a provider of the config file")
+	flag.StringVar(&configFile, "config", "", "path to a YAML config file of providers, filters and jobs, overrides provider settings from the environment")
+	flag.Func("job", "only run this job of the config file, may be repeated", func(s string) error {
+		jobNames = append(jobNames, s)
+		return nil
+	})
+	flag.BoolVar(&daemon, "daemon", false, "keep running and run every job at its schedule")
+	flag.StringVar(&scheduleSpec, "schedule", "", "cron expression, @daily style descriptor or @every duration at which -daemon mirrors -source to -target")
+	flag.IntVar(&concurrency, "concurrency", 5, "number of repos mirrored at once, unless set by the job")
+	flag.StringVar(&namespaceMode, "namespace-mode", mapping.Flat, "how source namespaces map to target paths: flat (repo), encode (group__repo) or preserve (group/repo, nested targets only)")
+	flag.StringVar(&cacheDir, "cache-dir", "", "directory of persistent bare mirrors, fetched incrementally instead of cloned on every run")
+	flag.StringVar(&workDir, "work-dir", filepath.Join(os.TempDir(), "mirror-git"), "directory for the run state and the clones of a run")
+	flag.BoolVar(&keepWorkDir, "keep-workdir", false, "keep the clones of a run in the work dir instead of removing them when the run ends")
+	flag.IntVar(&gracePeriod, "grace-period", 30, "seconds in-flight repos are given to finish after SIGINT or SIGTERM before they are cancelled")
+	flag.BoolVar(&resume, "resume", false, "resume an interrupted run, skipping repos that already succeeded in it")
+	flag.StringVar(&reportFile, "report", "", "write a JSON report of the outcome of every repo to this file")
+	flag.StringVar(&junitFile, "junit", "", "write a JUnit XML report with one testcase per repo to this file")
+	flag.StringVar(&summaryFile, "summary", "", "append a Markdown summary table to this file, e.g. $GITHUB_STEP_SUMMARY")
+	flag.BoolVar(&dryRun, "dry-run", false, "print which repos would be created and updated on the target without cloning or pushing")
+	flag.Func("include", "only mirror repos whose path with namespace matches this glob (** matches across namespaces) or re:regexp, may be repeated", func(s string) error {
+		filterOpts.Include = append(filterOpts.Include, s)
+		return nil
+	})
+	flag.Func("exclude", "skip repos whose path with namespace matches this glob or re:regexp, may be repeated", func(s string) error {
+		filterOpts.Exclude = append(filterOpts.Exclude, s)
+		return nil
+	})
+	flag.StringVar(&filterOpts.Visibility, "visibility", "", "only mirror repos of this visibility: all, public or private")
+	flag.BoolVar(&filterOpts.ExcludeForks, "exclude-forks", false, "skip forks")
+	flag.BoolVar(&filterOpts.ExcludeArchived, "exclude-archived", false, "skip archived repos")
+	flag.StringVar(&filterOpts.MaxSize, "max-size", "", "skip repos larger than this, e.g. 500MB")
+	flag.StringVar(&filterOpts.MaxPushAge, "max-push-age", "", "skip repos not pushed to within this age, e.g. 90d")
+	flag.StringVar(&retryFrom, "retry-from", "", "only mirror the repos that failed in this JSON report")
+	flag.IntVar(&maxRetries, "retries", retry.DefaultPolicy.MaxRetries, "how often a clone, push or API call is retried after a transient error")
+	flag.Float64Var(&rps, "rps", 10, "maximum API requests per second to each provider, 0 for no limit besides the rate limit headers")
 	flag.Parse()
 
-	var sourceGit types.SourceGit
-	switch sourceType {
-	case git.EGiteeV8:
-		sourceGit = e_gitee_v8.NewEnterpriseGiteeV8FromEnv()
-	case git.GitHub:
-		sourceGit = github.NewGitHubFromEnv()
-	default:
-		slog.Error("invalid source type", "type", sourceType)
-		os.Exit(1)
-	}
+	retryPolicy.MaxRetries = maxRetries
+	// The default handler cannot be wrapped, it writes through the log package which SetDefault
+	// points back at the new handler
+	slog.SetDefault(slog.New(redact.NewHandler(slog.NewTextHandler(os.Stderr, nil))))
 
-	var targetGit types.TargetGit
-	switch targetType {
-	case git.GitLab:
-		targetGit = gitlab.NewGitLabFromEnv()
-	case git.GitHub:
-		targetGit = github.NewGitHubFromEnv()
-	case git.Local:
-		targetGit = &local.Local{}
-	case git.Gitee:
-		targetGit = gitee.NewGiteeFromEnv()
-	default:
-		slog.Error("invalid target type", "type", targetType)
-		os.Exit(1)
+	cfg := &config.Config{}
+	if configFile != "" {
+		var err error
+		cfg, err = config.Load(configFile)
+		if err != nil {
+			slog.Error("load config failed", "error", err, "config", configFile)
+			os.Exit(1)
+		}
+		if err := cfg.Validate(); err != nil {
+			slog.Error("invalid config", "error", err, "config", configFile)
+			os.Exit(1)
+		}
 	}
 
-	workDir := filepath.Join(os.TempDir(), "/repos_"+time.Now().Format("20060102150405"))
-	if err := os.MkdirAll(workDir, 0755); err != nil {
-		slog.Error("create work dir failed", "error", err, "work_dir", workDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopping, stopSignals := handleSignals(cancel, time.Duration(gracePeriod)*time.Second)
+	defer stopSignals()
+
+	// Credentials are resolved while building the jobs, before any repo is mirrored
+	jobs, err := buildJobs(ctx, cfg)
+	if err != nil {
+		slog.Error("invalid jobs", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("work dir created", "dir", workDir)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
-	defer cancel()
+	if cacheDir != "" && !dryRun {
+		mirrorCache, err = cache.New(cacheDir)
+		if err != nil {
+			slog.Error("create cache failed", "error", err, "cache_dir", cacheDir)
+			os.Exit(1)
+		}
+		slog.Info("using mirror cache", "dir", mirrorCache.Dir)
+	}
 
-	err := runMirror(ctx, workDir, sourceGit, targetGit)
+	if daemon {
+		err = runDaemon(ctx, stopping, jobs)
+	} else {
+		err = runJobs(ctx, stopping, jobs)
+	}
 	if err != nil {
-		slog.Error("mirror failed", "error", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// handleSignals returns a context that is done on the first SIGINT or SIGTERM, telling the run
+// to stop scheduling repos. In-flight repos then get gracePeriod to finish before cancel is
+// called, a second signal calls it at once. The returned function stops handling signals.
+func handleSignals(cancel context.CancelFunc, gracePeriod time.Duration) (context.Context, func()) {
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	stopping, stopScheduling := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			slog.Warn("received signal, no longer scheduling repos", "signal", sig, "grace_period", gracePeriod)
+			stopScheduling()
+		case <-done:
+			return
+		}
+
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			slog.Warn("grace period is over, cancelling in-flight repos")
+		case sig := <-sigs:
+			slog.Warn("received signal again, cancelling in-flight repos", "signal", sig)
+		case <-done:
+			return
+		}
+		cancel()
+	}()
+
+	return stopping, func() {
+		signal.Stop(sigs)
+		close(done)
+		stopScheduling()
+	}
+}
+
+// applyProviderConfig points a provider at the hosts, namespace and source settings configured
+// in the config file
+func applyProviderConfig(g types.Git, p config.Provider) {
+	if s, ok := g.(types.SSHGit); ok && p.SSH != nil {
+		s.SetSSH(p.SSH)
+	}
+	switch g := g.(type) {
+	case *github.GitHub:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.SetAPIURL(p.APIURL)
+		}
+		if p.GraphQLURL != "" {
+			g.GraphQLAPI = p.GraphQLURL
+		}
+		if p.Namespace != "" {
+			g.Namespace = p.Namespace
+		}
+	case *gitlab.GitLab:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.BaseAPI = p.APIURL
+		}
+		if p.Namespace != "" {
+			g.Namespace = p.Namespace
+		}
+		if p.Group != "" {
+			g.Group = p.Group
+		}
+	case *gitee.Gitee:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.BaseAPI = p.APIURL
+		}
+		if p.Namespace != "" {
+			g.Namespace = p.Namespace
+		}
+		if p.Organization != "" {
+			g.Org = p.Organization
+		}
+	case *e_gitee_v8.EnterpriseGiteeV8:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.BaseAPI = p.APIURL
+		}
+		if p.EnterpriseID != "" {
+			g.EnterpriseId = p.EnterpriseID
+		}
+	case *gitea.Gitea:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.BaseAPI = p.APIURL
+		}
+		if p.Namespace != "" {
+			g.Org = p.Namespace
+		}
+		if p.Organization != "" {
+			g.Org = p.Organization
+		}
+	case *bitbucket.Bitbucket:
+		if p.BaseURL != "" {
+			g.SetBaseURL(p.BaseURL)
+		}
+		if p.APIURL != "" {
+			g.BaseAPI = p.APIURL
+		}
+		if p.Namespace != "" {
+			g.Project = p.Namespace
+		}
+	case *azuredevops.AzureDevOps:
+		if p.BaseURL != "" {
+			g.BaseURL = p.BaseURL
+		}
+		if p.Namespace != "" {
+			g.Project = p.Namespace
+		}
+		if p.Organization != "" {
+			g.Organization = p.Organization
+		}
+	case *urllist.URLList:
+		if p.File != "" {
+			g.File = p.File
+		}
+	case *local.Local:
+		if p.Dir != "" {
+			g.Dir = p.Dir
+		}
 	}
+}
+
+// tokenEnvs are the environment variables providers read their access token from
+var tokenEnvs = map[string]string{
+	git.EGiteeV8:    "E_GITEE_V8_ACCESS_TOKEN",
+	git.GitHub:      "GITHUB_ACCESS_TOKEN",
+	git.GitLab:      "GITLAB_ACCESS_TOKEN",
+	git.Gitee:       "GITEE_ACCESS_TOKEN",
+	git.Gitea:       "GITEA_ACCESS_TOKEN",
+	git.Bitbucket:   "BITBUCKET_ACCESS_TOKEN",
+	git.AzureDevOps: "AZURE_DEVOPS_ACCESS_TOKEN",
+}
 
-	if targetType != "local" {
-		slog.Info("cleaning up clone directory", "dir", workDir)
-		if err := os.RemoveAll(workDir); err != nil {
-			slog.Error("remove clone dir failed", "error", err, "clone_dir", workDir)
+// resolveCredentials sets the credentials of a provider. Without an access token in the environment
+// it is read from the file named by the _FILE variable, e.g. GITHUB_ACCESS_TOKEN_FILE. Credentials
+// referenced by the config replace those from the environment. The token is masked in all output.
+func resolveCredentials(ctx context.Context, g types.Git, c config.Credentials) error {
+	cg, ok := g.(types.CredentialGit)
+	if !ok {
+		return nil
+	}
+	username, token := cg.Credentials()
+	if token == "" {
+		var err error
+		if token, err = credential.Env(tokenEnvs[g.Name()]); err != nil {
+			return err
 		}
 	}
+	u, t, err := c.Resolve(ctx, cg.CredentialURL())
+	if err != nil {
+		return err
+	}
+	if u != "" {
+		username = u
+	}
+	if t != "" {
+		token = t
+	}
+	cg.SetCredentials(username, token)
+	redact.Add(token)
+	return nil
+}
+
+// mergeFilterOptions adds the filters given as flags to those of the config file,
+// flags take precedence over settings of the config file
+func mergeFilterOptions(cfg, flags filter.Options) filter.Options {
+	opts := cfg
+	opts.Include = append(opts.Include, flags.Include...)
+	opts.Exclude = append(opts.Exclude, flags.Exclude...)
+	if flags.Visibility != "" {
+		opts.Visibility = flags.Visibility
+	}
+	opts.ExcludeForks = opts.ExcludeForks || flags.ExcludeForks
+	opts.ExcludeArchived = opts.ExcludeArchived || flags.ExcludeArchived
+	if flags.MaxSize != "" {
+		opts.MaxSize = flags.MaxSize
+	}
+	if flags.MaxPushAge != "" {
+		opts.MaxPushAge = flags.MaxPushAge
+	}
+	return opts
 }
 
-func runMirror(ctx context.Context, workDir string, sourceGit types.SourceGit, targetGit types.TargetGit) (err error) {
-	allRepos, err := sourceGit.ListRepos()
+// sharedTransport is a rate limited transport and the requests per second it was created with
+type sharedTransport struct {
+	transport *ratelimit.Transport
+	rps       float64
+}
+
+// transports are keyed by the host and credentials of the providers using them
+var transports = make(map[string]sharedTransport)
+
+// limitRate makes the API requests of all workers to a provider share one rate limited transport.
+// Rate limits apply per account, so providers with the same host and credentials share it too,
+// at the requests per second of the first of them.
+func limitRate(g types.Git, p config.Provider) {
+	h, ok := g.(types.HTTPGit)
+	if !ok {
+		return
+	}
+	limit := rps
+	if p.RPS > 0 {
+		limit = p.RPS
+	}
+
+	key := g.Name()
+	if c, ok := g.(types.CredentialGit); ok {
+		username, token := c.Credentials()
+		key = strings.Join([]string{c.CredentialURL(), username, token}, "\x00")
+	}
+	shared, ok := transports[key]
+	if !ok {
+		shared = sharedTransport{transport: ratelimit.New(http.DefaultTransport, limit), rps: limit}
+		transports[key] = shared
+	} else if shared.rps != limit {
+		slog.Warn("providers with the same host and credentials share a rate limit, ignoring a different rps",
+			"provider", g.Name(), "rps", limit, "shared_rps", shared.rps)
+	}
+	h.SetTransport(shared.transport)
+}
+
+// runMirror mirrors all repos of the source to the targets. Once stopping is done no further repos
+// are started, those in flight run until they finish or ctx is done.
+func runMirror(ctx, stopping context.Context, run *jobRun, workDir string, sourceGit types.SourceGit, targets []jobTarget, mapper *mapping.Mapper) (err error) {
+	allRepos, skipped, err := listRepos(ctx, run, sourceGit)
 	if err != nil {
-		slog.Error("list repos failed", "error", err, "source", sourceType)
-		return fmt.Errorf("list repos failed: %w", err)
+		return err
 	}
-	if len(allRepos) == 0 {
-		slog.Info("no repos found", "source", sourceType)
+	if len(allRepos) == 0 && len(skipped) == 0 {
 		return nil
 	}
 
-	slog.Info("total repos", "count", len(allRepos), "source", sourceType)
+	// Fail before any push, otherwise git push --mirror lets one repo silently overwrite another
+	for _, t := range targets {
+		if collisions := mapper.FindCollisions(allRepos, t.git); len(collisions) > 0 {
+			for _, c := range collisions {
+				slog.Error("target path collision", "target_path", c.TargetPath, "repos", c.Sources, "target", t.name)
+			}
+			return fmt.Errorf("%d target paths on %s are claimed by more than one repo, use -namespace-mode %s or %s", len(collisions), t.name, mapping.Encode, mapping.Preserve)
+		}
+	}
 
-	failedRepos := make([][]string, 0)
-	failedReposLock := sync.Mutex{}
+	resumed, err := run.state.BeginRun(run.source, run.target, resume)
+	if err != nil {
+		return fmt.Errorf("save run state failed: %w", err)
+	}
+	if resumed {
+		slog.Info("resuming interrupted run", "state", run.state.Path())
+	} else if resume {
+		slog.Info("no interrupted run to resume, starting a new run", "state", run.state.Path())
+	}
+
+	rep := report.New(run.source, run.target)
+	for _, s := range skipped {
+		rep.Add(report.RepoResult{Repo: s.Repo, Status: report.StatusSkipped, Reason: s.Reason})
+	}
 
-	maxWorkers := 5
+	maxWorkers := max(run.concurrency, 1)
 	sem := make(chan struct{}, maxWorkers)
 	defer close(sem)
 
 	// Process allRepos as needed
-	for _, repo := range allRepos {
+	pending := 0
+	for i, repo := range allRepos {
 		// Check if context is already cancelled
 		select {
 		case <-ctx.Done():
 			slog.Warn("context cancelled, stopping repo processing", "error", ctx.Err())
+			pending = len(allRepos) - i
+			goto waitForCompletion
+		case <-stopping.Done():
+			pending = len(allRepos) - i
 			goto waitForCompletion
 		default:
 		}
 
-		sem <- struct{}{} // Acquire a token
+		if resumed && run.state.Succeeded(repo.GetPathWithNamespace()) {
+			slog.Info("repo already mirrored in the interrupted run, skip it", "repo", repo.GetPathWithNamespace())
+			rep.Add(report.RepoResult{
+				Repo:       repo.GetPathWithNamespace(),
+				TargetPath: mapper.TargetPath(repo),
+				Status:     report.StatusSkipped,
+				Reason:     "already mirrored in the interrupted run",
+			})
+			continue
+		}
+
+		// Acquire a token
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			slog.Warn("context cancelled, stopping repo processing", "error", ctx.Err())
+			pending = len(allRepos) - i
+			goto waitForCompletion
+		case <-stopping.Done():
+			pending = len(allRepos) - i
+			goto waitForCompletion
+		}
 
 		go func(r types.Repo) {
 			defer func() { <-sem }() // Release the token
 
-			err := mirrorRepo(ctx, workDir, r, sourceGit, targetGit)
-			if err != nil {
-				failedReposLock.Lock()
-				failedRepos = append(failedRepos, []string{r.GetPathWithNamespace(), err.Error()})
-				failedReposLock.Unlock()
+			if err := run.state.StartRepo(r.GetPathWithNamespace()); err != nil {
+				slog.Warn("save run state failed", "error", err)
+			}
+			results := make([]report.RepoResult, len(targets))
+			for i, t := range targets {
+				results[i] = report.RepoResult{
+					Repo:       r.GetPathWithNamespace(),
+					TargetPath: mapper.TargetPath(r),
+					StartedAt:  time.Now(),
+				}
+				if len(targets) > 1 {
+					results[i].Target = t.name
+				}
+			}
+			err := mirrorRepo(ctx, run, workDir, r, mapper.TargetPath(r), sourceGit, targets, results)
+			if err := run.state.FinishRepo(r.GetPathWithNamespace(), err); err != nil {
+				slog.Warn("save run state failed", "error", err)
+			}
+			for _, result := range results {
+				rep.Add(result)
 			}
 		}(repo)
 	}
 
 waitForCompletion:
 
+	if pending > 0 {
+		rep.Interrupt(pending)
+		slog.Warn("run interrupted, waiting for in-flight repos", "in_flight", len(sem), "not_started", pending,
+			"succeeded", rep.Succeeded, "failed", rep.Failed)
+	}
+
 	// Wait for all goroutines to finish
 	for range maxWorkers {
 		sem <- struct{}{}
 	}
 
-	if len(failedRepos) > 0 {
-		slog.Info("some repos mirror failed", "count", len(failedRepos))
-		for _, r := range failedRepos {
-			slog.Info("failed repo", "repo", r[0], "reason", r[1])
+	// Repos cut short by the timeout or the end of the grace period are left for -resume
+	completed := pending == 0 && ctx.Err() == nil
+	if err := run.state.EndRun(completed); err != nil {
+		slog.Warn("save run state failed", "error", err)
+	}
+
+	err = rep.Finish()
+	if pending > 0 && err == nil {
+		err = fmt.Errorf("run interrupted, %d repos were not mirrored", pending)
+	}
+	if err != nil {
+		slog.Info("some repos mirror failed", "count", rep.Failed)
+		for _, r := range rep.Repos {
+			if r.Status == report.StatusFailed {
+				slog.Info("failed repo", "repo", r.Repo, "target", r.Target, "phase", r.Phase, "reason", r.Error)
+			}
 		}
 	}
-	return nil
+
+	if reportFile != "" {
+		if err := rep.Write(run.outputPath(reportFile)); err != nil {
+			slog.Error("write report failed", "error", err, "report", run.outputPath(reportFile))
+		} else {
+			slog.Info("report written", "report", run.outputPath(reportFile))
+		}
+	}
+	if junitFile != "" {
+		if err := rep.WriteJUnit(run.outputPath(junitFile)); err != nil {
+			slog.Error("write junit report failed", "error", err, "junit", run.outputPath(junitFile))
+		} else {
+			slog.Info("junit report written", "junit", run.outputPath(junitFile))
+		}
+	}
+	if summaryFile != "" {
+		if err := rep.AppendMarkdown(summaryFile); err != nil {
+			slog.Error("write summary failed", "error", err, "summary", summaryFile)
+		} else {
+			slog.Info("summary written", "summary", summaryFile)
+		}
+	}
+	return err
 }
 
-func mirrorRepo(ctx context.Context, workDir string, repo types.Repo, source types.SourceGit, target types.TargetGit) (err error) {
+// listRepos lists the repos of the source that the run mirrors,
+// returning the repos left out by -retry-from and the filters as skipped
+func listRepos(ctx context.Context, run *jobRun, sourceGit types.SourceGit) ([]types.Repo, []plan.Skipped, error) {
+	var allRepos []types.Repo
+	_, err := retryPolicy.Do(ctx, "list repos", func() error {
+		var err error
+		allRepos, err = sourceGit.ListRepos(ctx)
+		return err
+	})
+	if err != nil {
+		slog.Error("list repos failed", "error", err, "source", run.source)
+		return nil, nil, fmt.Errorf("list repos failed: %w", err)
+	}
+	if len(allRepos) == 0 {
+		slog.Info("no repos found", "source", run.source)
+		return nil, nil, nil
+	}
+
+	slog.Info("total repos", "count", len(allRepos), "source", run.source)
+
+	var skipped []plan.Skipped
+	if retryFrom != "" {
+		failed, err := filterFailedRepos(allRepos, retryFrom)
+		if err != nil {
+			return nil, nil, err
+		}
+		kept := make(map[string]bool, len(failed))
+		for _, repo := range failed {
+			kept[repo.GetPathWithNamespace()] = true
+		}
+		for _, repo := range allRepos {
+			if !kept[repo.GetPathWithNamespace()] {
+				skipped = append(skipped, plan.Skipped{Repo: repo.GetPathWithNamespace(), Reason: "did not fail in " + retryFrom})
+			}
+		}
+		allRepos = failed
+		slog.Info("retrying failed repos", "count", len(allRepos), "report", retryFrom)
+	}
+
+	if run.filter != nil {
+		matched := make([]types.Repo, 0, len(allRepos))
+		for _, repo := range allRepos {
+			if ok, reason := run.filter.Match(repo); ok {
+				matched = append(matched, repo)
+			} else {
+				skipped = append(skipped, plan.Skipped{Repo: repo.GetPathWithNamespace(), Reason: reason})
+			}
+		}
+		if len(matched) < len(allRepos) {
+			slog.Info("filtered repos", "count", len(matched), "skipped", len(allRepos)-len(matched))
+		}
+		allRepos = matched
+	}
+	return allRepos, skipped, nil
+}
+
+// planMirror computes what runMirror would do, checking which target repos exist
+// but without cloning, creating or pushing anything
+func planMirror(ctx context.Context, run *jobRun, sourceGit types.SourceGit, targetGit types.TargetGit, mapper *mapping.Mapper) (*plan.Plan, error) {
+	allRepos, skipped, err := listRepos(ctx, run, sourceGit)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan.Plan{
+		Source:     run.source,
+		Target:     run.target,
+		Collisions: mapper.FindCollisions(allRepos, targetGit),
+		Skipped:    skipped,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(run.concurrency, 1))
+	for _, repo := range allRepos {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(r types.Repo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			item := plan.Item{
+				Repo:       r.GetPathWithNamespace(),
+				TargetPath: mapper.TargetPath(r),
+				Private:    r.GetPrivate(),
+			}
+			var exists bool
+			_, err := retryPolicy.Do(ctx, "check repo exist "+item.Repo, func() error {
+				var err error
+				exists, err = targetGit.IsRepoExist(ctx, item.TargetPath)
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				item.Error = redact.String(err.Error())
+				p.Errors = append(p.Errors, item)
+			case exists:
+				p.Update = append(p.Update, item)
+			default:
+				p.Create = append(p.Create, item)
+			}
+		}(repo)
+	}
+	wg.Wait()
+
+	p.Sort()
+	return p, nil
+}
+
+// filterFailedRepos keeps the repos that failed in a previous report
+func filterFailedRepos(repos []types.Repo, reportPath string) ([]types.Repo, error) {
+	previous, err := report.Load(reportPath)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]bool)
+	for _, repo := range previous.FailedRepos() {
+		failed[repo] = true
+	}
+
+	filtered := make([]types.Repo, 0, len(failed))
+	for _, repo := range repos {
+		if failed[repo.GetPathWithNamespace()] {
+			filtered = append(filtered, repo)
+			delete(failed, repo.GetPathWithNamespace())
+		}
+	}
+	for repo := range failed {
+		slog.Warn("failed repo no longer exists in source, skip it", "repo", repo)
+	}
+	return filtered, nil
+}
+
+// mirrorRepo clones a single repo once and pushes it to all targets concurrently, recording the
+// outcome for each target in the result of the same index. Errors are *report.PhaseError naming
+// the phase that failed, joined across the targets.
+func mirrorRepo(ctx context.Context, run *jobRun, workDir string, repo types.Repo, targetPath string, source types.SourceGit, targets []jobTarget, results []report.RepoResult) (err error) {
+	// The retries and size of the clone count for every target
+	clone := report.RepoResult{Repo: repo.GetPathWithNamespace()}
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("mirror panic: %v", r)
 		}
+		for i := range results {
+			results[i].Retries += clone.Retries
+			results[i].Bytes = clone.Bytes
+			// Targets that were never pushed to fail with the clone
+			if results[i].Status == "" {
+				finishResult(&results[i], err)
+			}
+		}
 	}()
 
+	if repoTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(repoTimeout)*time.Second)
+		defer cancel()
+	}
+
 	// Check if context is already cancelled
 	if err := ctx.Err(); err != nil {
-		return fmt.Errorf("context cancelled before starting: %w", err)
+		return &report.PhaseError{Phase: report.PhaseClone, Err: fmt.Errorf("context cancelled before starting: %w", err)}
 	}
 
-	slog.Info("mirror repo", "repo", repo.GetPathWithNamespace())
+	slog.Info("mirror repo", "repo", repo.GetPathWithNamespace(), "target_path", targetPath)
 
-	repoDir := workDir + "/" + repo.GetPath() + "_" + time.Now().Format("20060102150405")
+	gitUrl, gitEnv := gitRemote(source, source.GetSourceRepoAddr(repo.GetPathWithNamespace()))
+	local := hasLocalTarget(targets)
 
-	gitUrl := source.GetSourceRepoAddr(repo.GetPathWithNamespace())
+	var repoDir string
+	var entry *cache.Entry
+	if mirrorCache != nil && !local {
+		entry, err = mirrorCache.Lock(ctx, source.Name()+"/"+repo.GetPathWithNamespace())
+		if err != nil {
+			slog.Error("lock cache entry failed", "error", err, "repo", repo.GetPathWithNamespace())
+			return &report.PhaseError{Phase: report.PhaseClone, Err: err}
+		}
+		defer entry.Unlock()
 
-	var cloneCmd []string
-	if target.Name() == "local" {
-		cloneCmd = []string{"git", "clone", gitUrl, repoDir}
+		repoDir = entry.Dir
+		err = withRetry(ctx, &clone, "fetch", func(ctx context.Context) error {
+			return fetchCacheEntry(ctx, entry, gitUrl, gitEnv)
+		})
+		if err != nil {
+			return &report.PhaseError{Phase: report.PhaseClone, Err: err}
+		}
 	} else {
-		cloneCmd = []string{"git", "clone", "--bare", gitUrl, repoDir}
+		repoDir = workDir + "/" + targetPath + "_" + time.Now().Format("20060102150405")
+
+		var cloneCmd []string
+		if local {
+			cloneCmd = []string{"git", "clone", gitUrl, repoDir}
+		} else {
+			cloneCmd = []string{"git", "clone", "--bare", gitUrl, repoDir}
+		}
+
+		slog.Info("clone repo", "cmd", cloneCmd)
+		err = withRetry(ctx, &clone, "clone", func(ctx context.Context) error {
+			// Start over from an empty directory after a broken off clone
+			os.RemoveAll(repoDir)
+			return runGit(ctx, "", gitEnv, cloneCmd[1:]...)
+		})
+		if err != nil {
+			slog.Error("clone repo failed", "error", err, "cmd", cloneCmd)
+			return &report.PhaseError{Phase: report.PhaseClone, Err: err}
+		}
 	}
+	clone.Bytes = dirSize(repoDir)
 
-	slog.Info("clone repo", "cmd", cloneCmd)
-	cmd := exec.CommandContext(ctx, cloneCmd[0], cloneCmd[1:]...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	err = cmd.Run()
+	// Snapshot the mirrored refs for the cache and the run state
+	refs, err := gitOutput(ctx, repoDir, "for-each-ref", "--format=%(objectname) %(refname)")
 	if err != nil {
-		slog.Error("clone repo failed", "error", err, "cmd", cloneCmd)
-		return fmt.Errorf("clone failed: %w", err)
+		slog.Warn("list refs failed", "error", err, "repo", repo.GetPathWithNamespace())
+		refs = nil
 	}
 
-	exists, err := target.IsRepoExist(repo.GetPath())
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+	for i, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("mirror panic: %v", r)
+				}
+				finishResult(&results[i], errs[i])
+			}()
+			errs[i] = pushRepo(ctx, repoDir, entry, refs, repo, targetPath, t, &results[i])
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if refs != nil && run.state != nil {
+		if err := run.state.SetRefs(repo.GetPathWithNamespace(), parseRefs(refs)); err != nil {
+			slog.Warn("save run state failed", "error", err)
+		}
+	}
+	slog.Info("mirror repo success", "repo", repo.GetPathWithNamespace())
+	return nil
+}
+
+// pushRepo creates the repo on a target if it does not exist and pushes the clone in repoDir to it
+func pushRepo(ctx context.Context, repoDir string, entry *cache.Entry, refs []byte, repo types.Repo, targetPath string, t jobTarget, result *report.RepoResult) error {
+	target := t.git
+
+	var exists bool
+	err := withRetry(ctx, result, "check repo exist", func(ctx context.Context) error {
+		var err error
+		exists, err = target.IsRepoExist(ctx, targetPath)
+		return err
+	})
 	if err != nil {
-		slog.Error("check repo exist failed", "error", err, "repo", repo)
-		return fmt.Errorf("check exist failed: %w", err)
+		slog.Error("check repo exist failed", "error", err, "repo", repo, "target", t.name)
+		return &report.PhaseError{Phase: report.PhaseExists, Err: err}
 	}
 	if !exists {
-		slog.Info("repo not exists, create it", "repo", repo.GetPath())
-		err := target.CreateRepo(repo.GetPath(), repo.GetDesc(), repo.GetPrivate())
+		slog.Info("repo not exists, create it", "repo", targetPath, "target", t.name)
+		err := withRetry(ctx, result, "create repo", func(ctx context.Context) error {
+			return target.CreateRepo(ctx, targetPath, repo.GetDesc(), repo.GetPrivate())
+		})
 		if err != nil {
-			slog.Error("create repo failed", "error", err, "repo", repo)
-			return fmt.Errorf("create failed: %w", err)
+			slog.Error("create repo failed", "error", err, "repo", repo, "target", t.name)
+			return &report.PhaseError{Phase: report.PhaseCreate, Err: err}
 		}
 	}
 
-	pushAddr := target.GetTargetRepoAddr(repo.GetPath())
-	if pushAddr != "" {
-		pushCmd := []string{
-			"git", "push", "--mirror", pushAddr,
+	pushAddr, pushEnv := gitRemote(target, target.GetTargetRepoAddr(ctx, targetPath))
+	if pushAddr == "" {
+		return nil
+	}
+
+	// With a cache, skip targets that already have exactly the refs of the mirror
+	var refsDigest string
+	pushedKey := t.name + ":" + targetPath
+	if entry != nil && refs != nil {
+		refsDigest = cache.RefsDigest(refs)
+		if exists && refsDigest == entry.PushedDigest(pushedKey) {
+			slog.Info("repo unchanged since last push, skip it", "repo", repo.GetPathWithNamespace(), "target", t.name)
+			return nil
 		}
-		slog.Info("push repo", "cmd", pushCmd)
-		cmd = exec.CommandContext(ctx, pushCmd[0], pushCmd[1:]...)
-		cmd.Dir = repoDir
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err = cmd.Run()
-		if err != nil {
-			slog.Error("push repo failed", "error", err, "cmd", pushCmd)
-			return fmt.Errorf("push failed: %w", err)
+	}
+
+	if p, ok := target.(types.PrePushGit); ok && exists {
+		if err := p.PreparePush(ctx, targetPath); err != nil {
+			slog.Warn("prepare push failed", "error", err, "repo", targetPath, "target", t.name)
 		}
 	}
 
-	slog.Info("mirror repo success", "repo", repo.GetPathWithNamespace())
+	pushCmd := []string{
+		"git", "push", "--mirror", pushAddr,
+	}
+	slog.Info("push repo", "cmd", pushCmd)
+	err = withRetry(ctx, result, "push", func(ctx context.Context) error {
+		return runGit(ctx, repoDir, pushEnv, pushCmd[1:]...)
+	})
+	if err != nil {
+		slog.Error("push repo failed", "error", err, "cmd", pushCmd)
+		return &report.PhaseError{Phase: report.PhasePush, Err: err}
+	}
+
+	if refsDigest != "" {
+		if err := entry.SetPushedDigest(pushedKey, refsDigest); err != nil {
+			slog.Warn("record pushed refs failed", "error", err, "repo", repo.GetPathWithNamespace())
+		}
+	}
+	return nil
+}
+
+// finishResult records the outcome of mirroring a repo to a target
+func finishResult(result *report.RepoResult, err error) {
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+	if err == nil {
+		result.Status = report.StatusSuccess
+		return
+	}
+	result.Status = report.StatusFailed
+	result.Error = err.Error()
+	var phaseErr *report.PhaseError
+	if errors.As(err, &phaseErr) {
+		result.Phase = phaseErr.Phase
+	}
+}
+
+// withRetry runs a phase of mirroring a repo under the retry policy and the phase timeout,
+// adding the retries made to result
+func withRetry(ctx context.Context, result *report.RepoResult, op string, fn func(ctx context.Context) error) error {
+	if phaseTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(phaseTimeout)*time.Second)
+		defer cancel()
+	}
+	retries, err := retryPolicy.Do(ctx, op+" "+result.Repo, func() error {
+		return fn(ctx)
+	})
+	result.Retries += retries
+	return err
+}
 
+// fetchCacheEntry clones a mirror into an empty cache entry or updates an existing one,
+// so that only new objects are transferred from the source
+func fetchCacheEntry(ctx context.Context, entry *cache.Entry, gitUrl string, gitEnv []string) error {
+	if entry.Exists() {
+		slog.Info("update cached mirror", "dir", entry.Dir)
+		// The URL may have changed since the entry was cloned, and entries cloned by earlier
+		// versions have credentials embedded in it
+		if err := runGit(ctx, entry.Dir, nil, "remote", "set-url", "origin", gitUrl); err != nil {
+			slog.Error("set cached mirror url failed", "error", err, "dir", entry.Dir)
+			return err
+		}
+		if err := runGit(ctx, entry.Dir, gitEnv, "remote", "update", "--prune"); err != nil {
+			slog.Error("update cached mirror failed", "error", err, "dir", entry.Dir)
+			return err
+		}
+		return nil
+	}
+
+	slog.Info("clone repo into cache", "dir", entry.Dir)
+	if err := runGit(ctx, "", gitEnv, "clone", "--mirror", gitUrl, entry.Dir); err != nil {
+		slog.Error("clone repo into cache failed", "error", err, "dir", entry.Dir)
+		// Do not leave a half cloned mirror behind for the next run to update
+		if err := entry.Remove(); err != nil {
+			slog.Error("remove cache entry failed", "error", err, "dir", entry.Dir)
+		}
+		return err
+	}
+	return nil
+}
+
+// dirSize returns the total size of the files below dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// parseRefs parses the output of git for-each-ref --format='%(objectname) %(refname)'
+func parseRefs(out []byte) map[string]string {
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		hash, name, ok := strings.Cut(line, " ")
+		if ok {
+			refs[name] = hash
+		}
+	}
+	return refs
+}
+
+// maxGitErrorOutput is how much of the end of the git stderr is kept in errors
+const maxGitErrorOutput = 1024
+
+// runGit runs a git command in dir with env added to its environment, streaming its output
+// to the console with secrets masked. Errors include the end of the stderr so that they can be
+// classified for retries.
+func runGit(ctx context.Context, dir string, env []string, args ...string) error {
+	var stderr bytes.Buffer
+	stdout, console := redact.NewWriter(os.Stdout), redact.NewWriter(os.Stderr)
+	defer stdout.Flush()
+	defer console.Flush()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(console, &stderr)
+	if err := cmd.Run(); err != nil {
+		out := stderr.Bytes()
+		if len(out) > maxGitErrorOutput {
+			out = out[len(out)-maxGitErrorOutput:]
+		}
+		return fmt.Errorf("git %s failed: %w: %s", args[0], err, redact.String(strings.TrimSpace(string(out))))
+	}
 	return nil
 }
+
+// gitRemote returns the URL of a remote of provider g and the environment git needs to access it.
+// Providers using SSH get their key and known hosts, otherwise the credentials are taken out of
+// the URL so that they do not show up in process listings, logs or the config of clones.
+func gitRemote(g types.Git, repoURL string) (string, []string) {
+	if s, ok := g.(types.SSHGit); ok && s.SSHConfig() != nil {
+		return repoURL, s.SSHConfig().Env()
+	}
+	cleanURL, username, password := git.SplitCredentials(repoURL)
+	if username == "" && password == "" {
+		return cleanURL, nil
+	}
+	redact.Add(password, git.BasicAuth(username, password))
+	return cleanURL, git.CredentialEnv(username, password)
+}
+
+// gitOutput runs a git command in dir and returns its standard output,
+// its stderr goes to the console with secrets masked
+func gitOutput(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	console := redact.NewWriter(os.Stderr)
+	defer console.Flush()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = console
+	return cmd.Output()
+}
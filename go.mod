@@ -2,7 +2,10 @@ module github.com/k8scat/mirror-git-go
 
 go 1.25.1
 
-require github.com/tidwall/gjson v1.18.0
+require (
+	github.com/tidwall/gjson v1.18.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/tidwall/match v1.2.0 // indirect
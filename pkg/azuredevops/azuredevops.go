@@ -0,0 +1,329 @@
+package azuredevops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+var _ types.TargetGit = &AzureDevOps{}
+var _ types.HTTPGit = &AzureDevOps{}
+var _ types.SSHGit = &AzureDevOps{}
+var _ types.CredentialGit = &AzureDevOps{}
+var _ types.SourceGit = &AzureDevOps{}
+
+const apiVersion = "7.1"
+
+// AzureDevOps is a client for Azure DevOps Repos.
+// Repositories are addressed as project/repo within an organization.
+type AzureDevOps struct {
+	// AccessToken is a personal access token, sent with basic auth
+	AccessToken string
+	// Username is only used in clone URLs, Azure DevOps ignores its value
+	Username     string
+	Organization string
+	// Project is the project used to create repositories and,
+	// when set, to limit ListRepos to a single project.
+	Project string
+	// BaseURL is the service URL, e.g. https://dev.azure.com
+	BaseURL string
+	client  *http.Client
+	ssh     *git.SSH
+}
+
+// NewAzureDevOps creates a new Azure DevOps client
+func NewAzureDevOps(organization, accessToken string) *AzureDevOps {
+	return &AzureDevOps{
+		AccessToken:  accessToken,
+		Username:     "pat",
+		Organization: organization,
+		BaseURL:      "https://dev.azure.com",
+		client:       &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// NewAzureDevOpsFromEnv creates a new Azure DevOps client from environment variables
+func NewAzureDevOpsFromEnv() *AzureDevOps {
+	a := NewAzureDevOps(os.Getenv("AZURE_DEVOPS_ORGANIZATION"), os.Getenv("AZURE_DEVOPS_ACCESS_TOKEN"))
+	a.Project = os.Getenv("AZURE_DEVOPS_PROJECT")
+	if username := os.Getenv("AZURE_DEVOPS_USERNAME"); username != "" {
+		a.Username = username
+	}
+	if baseURL := os.Getenv("AZURE_DEVOPS_BASE_URL"); baseURL != "" {
+		a.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	return a
+}
+
+func (a *AzureDevOps) Name() string {
+	return "azuredevops"
+}
+
+// Project represents a team project returned by the Azure DevOps API
+type Project struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+}
+
+// Repo represents a Git repository returned by the Azure DevOps API
+type Repo struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Size       int64   `json:"size"`
+	IsDisabled bool    `json:"isDisabled"`
+	IsFork     bool    `json:"isFork"`
+	Project    Project `json:"project"`
+}
+
+type CreateRepoRequest struct {
+	Name    string `json:"name"`
+	Project struct {
+		ID string `json:"id"`
+	} `json:"project"`
+}
+
+// apiURL builds an organization scoped REST API URL
+func (a *AzureDevOps) apiURL(path string, queries url.Values) string {
+	if queries == nil {
+		queries = url.Values{}
+	}
+	queries.Set("api-version", apiVersion)
+	return fmt.Sprintf("%s/%s/%s?%s", strings.TrimSuffix(a.BaseURL, "/"), url.PathEscape(a.Organization), path, queries.Encode())
+}
+
+func (a *AzureDevOps) do(ctx context.Context, method, api string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, api, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth("", a.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// get sends a GET request and decodes the JSON response into v,
+// returning the continuation token of paged responses if any.
+func (a *AzureDevOps) get(ctx context.Context, api string, v any) (string, error) {
+	resp, err := a.do(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", types.NewStatusError(resp.StatusCode, fmt.Errorf("request failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return resp.Header.Get("X-Ms-Continuationtoken"), nil
+}
+
+// ListProjects lists all projects of the organization
+// https://learn.microsoft.com/en-us/rest/api/azure/devops/core/projects/list
+func (a *AzureDevOps) ListProjects(ctx context.Context) ([]Project, error) {
+	allProjects := make([]Project, 0)
+	continuationToken := ""
+	for {
+		queries := url.Values{}
+		queries.Set("$top", "100")
+		if continuationToken != "" {
+			queries.Set("continuationToken", continuationToken)
+		}
+
+		var result struct {
+			Value []Project `json:"value"`
+		}
+		next, err := a.get(ctx, a.apiURL("_apis/projects", queries), &result)
+		if err != nil {
+			return nil, err
+		}
+		allProjects = append(allProjects, result.Value...)
+		if next == "" {
+			break
+		}
+		continuationToken = next
+	}
+	return allProjects, nil
+}
+
+// ListRepos implements types.SourceGit.
+// https://learn.microsoft.com/en-us/rest/api/azure/devops/git/repositories/list
+func (a *AzureDevOps) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	projects := make([]string, 0)
+	if a.Project != "" {
+		projects = append(projects, a.Project)
+	} else {
+		all, err := a.ListProjects(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list projects failed: %w", err)
+		}
+		for _, p := range all {
+			projects = append(projects, p.Name)
+		}
+	}
+
+	allRepos := make([]types.Repo, 0)
+	for _, project := range projects {
+		var result struct {
+			Value []Repo `json:"value"`
+		}
+		if _, err := a.get(ctx, a.apiURL(url.PathEscape(project)+"/_apis/git/repositories", nil), &result); err != nil {
+			return nil, fmt.Errorf("list repos of project %s failed: %w", project, err)
+		}
+		for _, r := range result.Value {
+			if r.IsDisabled {
+				continue
+			}
+			allRepos = append(allRepos, &types.RepoImpl{
+				Path:              r.Name,
+				PathWithNamespace: r.Project.Name + "/" + r.Name,
+				Private:           r.Project.Visibility != "public",
+				Fork:              r.IsFork,
+				Size:              r.Size,
+			})
+		}
+	}
+	return allRepos, nil
+}
+
+// IsRepoExist implements types.TargetGit.
+func (a *AzureDevOps) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
+	api := a.apiURL(fmt.Sprintf("%s/_apis/git/repositories/%s", url.PathEscape(a.Project), url.PathEscape(repoName)), nil)
+	resp, err := a.do(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, types.NewStatusError(resp.StatusCode, fmt.Errorf("error checking repo: status=%d, body=%s", resp.StatusCode, string(respBody)))
+	}
+}
+
+// CreateRepo implements types.TargetGit.
+// Azure DevOps has no per-repository description or visibility,
+// both are inherited from the project.
+// https://learn.microsoft.com/en-us/rest/api/azure/devops/git/repositories/create
+func (a *AzureDevOps) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
+	if a.Project == "" {
+		return fmt.Errorf("project is required to create repositories")
+	}
+
+	var project Project
+	if _, err := a.get(ctx, a.apiURL("_apis/projects/"+url.PathEscape(a.Project), nil), &project); err != nil {
+		return fmt.Errorf("get project failed: %w", err)
+	}
+
+	payload := CreateRepoRequest{Name: name}
+	payload.Project.ID = project.ID
+
+	resp, err := a.do(ctx, http.MethodPost, a.apiURL(url.PathEscape(a.Project)+"/_apis/git/repositories", nil), payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("create repo failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+	return nil
+}
+
+// repoAddr returns the HTTPS clone URL of a repository with the credentials embedded,
+// or its SSH URL when SSH is configured.
+func (a *AzureDevOps) repoAddr(project, repo string) string {
+	u, err := url.Parse(a.BaseURL)
+	if err != nil {
+		return ""
+	}
+	if a.ssh != nil {
+		// Azure DevOps Services serves SSH from its own host with a v3 prefix,
+		// Azure DevOps Server from the instance with _ssh in place of _git
+		org, project, repo := url.PathEscape(a.Organization), url.PathEscape(project), url.PathEscape(repo)
+		if u.Hostname() == "dev.azure.com" {
+			return a.ssh.RepoURL("https://ssh.dev.azure.com", fmt.Sprintf("v3/%s/%s/%s", org, project, repo))
+		}
+		return a.ssh.RepoURL(a.BaseURL, fmt.Sprintf("%s/%s/%s/_ssh/%s", u.Path, org, project, repo))
+	}
+	u.User = url.UserPassword(a.Username, a.AccessToken)
+	u.Path = fmt.Sprintf("%s/%s/%s/_git/%s", strings.TrimSuffix(u.Path, "/"), a.Organization, project, repo)
+	return u.String()
+}
+
+// GetTargetRepoAddr implements types.TargetGit.
+func (a *AzureDevOps) GetTargetRepoAddr(ctx context.Context, path string) string {
+	return a.repoAddr(a.Project, path)
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+func (a *AzureDevOps) GetSourceRepoAddr(pathWithNamespace string) string {
+	project, repo, _ := strings.Cut(pathWithNamespace, "/")
+	return a.repoAddr(project, repo)
+}
+
+// SetTransport implements types.HTTPGit.
+func (a *AzureDevOps) SetTransport(rt http.RoundTripper) {
+	a.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (a *AzureDevOps) SetSSH(ssh *git.SSH) {
+	a.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (a *AzureDevOps) SSHConfig() *git.SSH {
+	return a.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (a *AzureDevOps) Credentials() (string, string) {
+	return a.Username, a.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (a *AzureDevOps) SetCredentials(username, token string) {
+	a.Username, a.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (a *AzureDevOps) CredentialURL() string {
+	return a.BaseURL
+}
@@ -0,0 +1,315 @@
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+var _ types.TargetGit = &Bitbucket{}
+var _ types.HTTPGit = &Bitbucket{}
+var _ types.SSHGit = &Bitbucket{}
+var _ types.CredentialGit = &Bitbucket{}
+var _ types.SourceGit = &Bitbucket{}
+
+// Bitbucket is a client for Bitbucket Server and Data Center.
+// Repositories are addressed as PROJECT_KEY/repo-slug.
+type Bitbucket struct {
+	AccessToken string
+	Username    string
+	// BaseURL is the web URL of the instance, e.g. https://bitbucket.example.com
+	BaseURL string
+	BaseAPI string
+	// Project is the project key used to create repositories and,
+	// when set, to limit ListRepos to a single project.
+	Project string
+	client  *http.Client
+	ssh     *git.SSH
+}
+
+// NewBitbucket creates a new Bitbucket Server client
+func NewBitbucket(baseURL, username, accessToken string) *Bitbucket {
+	b := &Bitbucket{
+		AccessToken: accessToken,
+		Username:    username,
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}
+	b.SetBaseURL(baseURL)
+	return b
+}
+
+// NewBitbucketFromEnv creates a new Bitbucket Server client from environment variables
+func NewBitbucketFromEnv() *Bitbucket {
+	b := NewBitbucket(os.Getenv("BITBUCKET_BASE_URL"), os.Getenv("BITBUCKET_USERNAME"), os.Getenv("BITBUCKET_ACCESS_TOKEN"))
+	b.Project = os.Getenv("BITBUCKET_PROJECT")
+	if apiURL := os.Getenv("BITBUCKET_API_URL"); apiURL != "" {
+		b.BaseAPI = strings.TrimSuffix(apiURL, "/")
+	}
+	return b
+}
+
+// SetBaseURL points the client at an instance, deriving the REST API URL from it
+func (b *Bitbucket) SetBaseURL(baseURL string) {
+	b.BaseURL = strings.TrimSuffix(baseURL, "/")
+	b.BaseAPI = b.BaseURL + "/rest/api/1.0"
+}
+
+func (b *Bitbucket) Name() string {
+	return "bitbucket"
+}
+
+// Project represents a project returned by the Bitbucket Server API
+type Project struct {
+	ID   int    `json:"id"`
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// Repo represents a repository returned by the Bitbucket Server API
+type Repo struct {
+	ID          int     `json:"id"`
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Public      bool    `json:"public"`
+	Archived    bool    `json:"archived"`
+	Project     Project `json:"project"`
+	Origin      *struct {
+		Slug string `json:"slug"`
+	} `json:"origin"`
+}
+
+// page is the envelope of paged Bitbucket Server responses
+// https://developer.atlassian.com/server/bitbucket/rest/v811/intro/#paged-apis
+type page[T any] struct {
+	Size          int  `json:"size"`
+	Limit         int  `json:"limit"`
+	IsLastPage    bool `json:"isLastPage"`
+	Start         int  `json:"start"`
+	NextPageStart int  `json:"nextPageStart"`
+	Values        []T  `json:"values"`
+}
+
+type CreateRepoRequest struct {
+	Name        string `json:"name"`
+	ScmID       string `json:"scmId"`
+	Description string `json:"description"`
+	Public      bool   `json:"public"`
+}
+
+func (b *Bitbucket) do(ctx context.Context, method, api string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, api, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// listAll fetches every page of a paged API using the start/limit parameters.
+func listAll[T any](ctx context.Context, b *Bitbucket, api string) ([]T, error) {
+	all := make([]T, 0)
+	start := 0
+	limit := 100
+	for {
+		queries := url.Values{}
+		queries.Set("start", fmt.Sprintf("%d", start))
+		queries.Set("limit", fmt.Sprintf("%d", limit))
+
+		resp, err := b.do(ctx, http.MethodGet, api+"?"+queries.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("list failed, status: %s, body: %s", resp.Status, string(respBody)))
+		}
+
+		var p page[T]
+		err = json.NewDecoder(resp.Body).Decode(&p)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		all = append(all, p.Values...)
+		if p.IsLastPage || len(p.Values) == 0 {
+			break
+		}
+		start = p.NextPageStart
+	}
+	return all, nil
+}
+
+// ListProjects lists all projects visible to the user
+func (b *Bitbucket) ListProjects(ctx context.Context) ([]Project, error) {
+	return listAll[Project](ctx, b, b.BaseAPI+"/projects")
+}
+
+// ListRepos implements types.SourceGit.
+func (b *Bitbucket) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	projectKeys := make([]string, 0)
+	if b.Project != "" {
+		projectKeys = append(projectKeys, b.Project)
+	} else {
+		projects, err := b.ListProjects(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list projects failed: %w", err)
+		}
+		for _, p := range projects {
+			projectKeys = append(projectKeys, p.Key)
+		}
+	}
+
+	allRepos := make([]types.Repo, 0)
+	for _, key := range projectKeys {
+		repos, err := listAll[Repo](ctx, b, fmt.Sprintf("%s/projects/%s/repos", b.BaseAPI, url.PathEscape(key)))
+		if err != nil {
+			return nil, fmt.Errorf("list repos of project %s failed: %w", key, err)
+		}
+		for _, r := range repos {
+			allRepos = append(allRepos, &types.RepoImpl{
+				Path:              r.Slug,
+				PathWithNamespace: r.Project.Key + "/" + r.Slug,
+				Desc:              r.Description,
+				Private:           !r.Public,
+				Fork:              r.Origin != nil,
+				Archived:          r.Archived,
+			})
+		}
+	}
+	return allRepos, nil
+}
+
+// IsRepoExist implements types.TargetGit.
+func (b *Bitbucket) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
+	api := fmt.Sprintf("%s/projects/%s/repos/%s", b.BaseAPI, url.PathEscape(b.Project), url.PathEscape(slugify(repoName)))
+	resp, err := b.do(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, types.NewStatusError(resp.StatusCode, fmt.Errorf("error checking repo: status=%d, body=%s", resp.StatusCode, string(respBody)))
+	}
+}
+
+// CreateRepo implements types.TargetGit.
+func (b *Bitbucket) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
+	if b.Project == "" {
+		return fmt.Errorf("project key is required to create repositories")
+	}
+
+	payload := CreateRepoRequest{
+		Name:        name,
+		ScmID:       "git",
+		Description: desc,
+		Public:      !private,
+	}
+
+	api := fmt.Sprintf("%s/projects/%s/repos", b.BaseAPI, url.PathEscape(b.Project))
+	resp, err := b.do(ctx, http.MethodPost, api, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("create repo failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+	return nil
+}
+
+// slugify mirrors how Bitbucket Server derives a repository slug from its name.
+func slugify(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), " ", "-"))
+}
+
+// repoAddr returns the HTTP(S) clone URL of a repository with the credentials embedded,
+// or its SSH URL when SSH is configured.
+func (b *Bitbucket) repoAddr(projectKey, slug string) string {
+	// Only HTTP(S) URLs have the scm prefix
+	if b.ssh != nil {
+		return b.ssh.RepoURL(b.BaseURL, strings.ToLower(projectKey)+"/"+slug+".git")
+	}
+	return git.RepoURL(b.BaseURL, b.Username, b.AccessToken, "scm/"+strings.ToLower(projectKey)+"/"+slug)
+}
+
+// GetTargetRepoAddr implements types.TargetGit.
+func (b *Bitbucket) GetTargetRepoAddr(ctx context.Context, path string) string {
+	return b.repoAddr(b.Project, slugify(path))
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+func (b *Bitbucket) GetSourceRepoAddr(pathWithNamespace string) string {
+	projectKey, slug, _ := strings.Cut(pathWithNamespace, "/")
+	return b.repoAddr(projectKey, slug)
+}
+
+// SetTransport implements types.HTTPGit.
+func (b *Bitbucket) SetTransport(rt http.RoundTripper) {
+	b.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (b *Bitbucket) SetSSH(ssh *git.SSH) {
+	b.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (b *Bitbucket) SSHConfig() *git.SSH {
+	return b.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (b *Bitbucket) Credentials() (string, string) {
+	return b.Username, b.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (b *Bitbucket) SetCredentials(username, token string) {
+	b.Username, b.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (b *Bitbucket) CredentialURL() string {
+	return b.BaseURL
+}
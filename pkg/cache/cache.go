@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockPollInterval is how often a busy cache entry is retried
+const lockPollInterval = 200 * time.Millisecond
+
+// Cache is a directory of bare mirror clones kept between runs
+type Cache struct {
+	Dir string
+}
+
+func New(dir string) (*Cache, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve cache dir failed: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("create cache dir failed: %w", err)
+	}
+	return &Cache{Dir: abs}, nil
+}
+
+// Entry is a locked cache entry. It must be released with Unlock.
+type Entry struct {
+	// Dir is the bare mirror repository of the entry
+	Dir string
+
+	lock      *os.File
+	stateFile string
+	// mu serializes the goroutines pushing the entry to different targets on its state file
+	mu sync.Mutex
+}
+
+// entryState is stored next to the mirror repository
+type entryState struct {
+	// Pushed maps a target key to the refs digest last pushed to it
+	Pushed map[string]string `json:"pushed"`
+}
+
+// Lock returns the entry for key (e.g. source/group/repo), waiting until no other
+// process or goroutine holds it or ctx is done.
+func (c *Cache) Lock(ctx context.Context, key string) (*Entry, error) {
+	key = strings.Trim(key, "/")
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("invalid cache key %q", key)
+		}
+	}
+	key = filepath.FromSlash(key)
+
+	dir := filepath.Join(c.Dir, key+".git")
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, fmt.Errorf("create cache entry dir failed: %w", err)
+	}
+
+	lockPath := dir + ".lock"
+	for {
+		f, err := tryLock(lockPath)
+		if err == nil {
+			return &Entry{Dir: dir, lock: f, stateFile: dir + ".state.json"}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("lock cache entry failed: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for cache entry lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Unlock releases the entry
+func (e *Entry) Unlock() error {
+	return unlock(e.lock)
+}
+
+// Exists reports whether the mirror repository has been cloned
+func (e *Entry) Exists() bool {
+	fi, err := os.Stat(filepath.Join(e.Dir, "HEAD"))
+	return err == nil && !fi.IsDir()
+}
+
+// Remove deletes a broken mirror repository so that it is cloned again
+func (e *Entry) Remove() error {
+	if err := os.RemoveAll(e.Dir); err != nil {
+		return err
+	}
+	return os.RemoveAll(e.stateFile)
+}
+
+// RefsDigest returns a digest of a ref listing, e.g. the output of git for-each-ref
+func RefsDigest(refs []byte) string {
+	sum := sha256.Sum256(refs)
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *Entry) readState() (*entryState, error) {
+	state := &entryState{Pushed: make(map[string]string)}
+	data, err := os.ReadFile(e.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", e.stateFile, err)
+	}
+	if state.Pushed == nil {
+		state.Pushed = make(map[string]string)
+	}
+	return state, nil
+}
+
+// PushedDigest returns the refs digest last pushed to target, or "" if unknown
+func (e *Entry) PushedDigest(target string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.readState()
+	if err != nil {
+		return ""
+	}
+	return state.Pushed[target]
+}
+
+// SetPushedDigest records the refs digest pushed to target, it is safe for concurrent use
+func (e *Entry) SetPushedDigest(target, digest string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	state, err := e.readState()
+	if err != nil {
+		state = &entryState{Pushed: make(map[string]string)}
+	}
+	state.Pushed[target] = digest
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := e.stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, e.stateFile)
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := c.Lock(context.Background(), "github/group/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(c.Dir, "github", "group", "api.git"); entry.Dir != want {
+		t.Errorf("entry dir = %q, want %q", entry.Dir, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockPollInterval)
+	defer cancel()
+	if _, err := c.Lock(ctx, "github/group/api"); err == nil {
+		t.Fatal("expected a held entry to block until the context is done")
+	}
+
+	acquired := make(chan *Entry)
+	go func() {
+		e, err := c.Lock(context.Background(), "github/group/api")
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- e
+	}()
+	time.Sleep(lockPollInterval / 2)
+	if err := entry.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case e := <-acquired:
+		e.Unlock()
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry was not acquired after unlock")
+	}
+
+	if _, err := c.Lock(context.Background(), "../escape"); err == nil {
+		t.Error("expected error for key escaping the cache dir")
+	}
+}
+
+func TestPushedDigest(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := c.Lock(context.Background(), "gitlab/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entry.Unlock()
+
+	if entry.Exists() {
+		t.Error("new entry should not exist")
+	}
+	if err := os.MkdirAll(entry.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(entry.Dir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Exists() {
+		t.Error("entry with HEAD should exist")
+	}
+
+	digest := RefsDigest([]byte("abc refs/heads/main\n"))
+	if got := entry.PushedDigest("github:api"); got != "" {
+		t.Errorf("PushedDigest() = %q, want empty", got)
+	}
+	if err := entry.SetPushedDigest("github:api", digest); err != nil {
+		t.Fatal(err)
+	}
+	if got := entry.PushedDigest("github:api"); got != digest {
+		t.Errorf("PushedDigest() = %q, want %q", got, digest)
+	}
+	if got := entry.PushedDigest("gitee:api"); got != "" {
+		t.Errorf("PushedDigest() for another target = %q, want empty", got)
+	}
+}
+
+func TestSetPushedDigestConcurrent(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := c.Lock(context.Background(), "gitlab/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entry.Unlock()
+
+	// Enough targets that unsynchronized read-modify-write cycles overlap
+	var targets []string
+	for i := range 50 {
+		targets = append(targets, fmt.Sprintf("target%d:api", i))
+	}
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := entry.SetPushedDigest(target, "digest-"+target); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, target := range targets {
+		if got := entry.PushedDigest(target); got != "digest-"+target {
+			t.Errorf("PushedDigest(%q) = %q, want the digest set concurrently", target, got)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+)
+
+var errLocked = errors.New("cache entry is locked")
+
+// tryLock creates path exclusively. Unlike flock, a lock file left behind
+// by a crashed process has to be removed by hand.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return nil, errLocked
+	}
+	return f, err
+}
+
+func unlock(f *os.File) error {
+	f.Close()
+	return os.Remove(f.Name())
+}
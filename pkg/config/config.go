@@ -0,0 +1,299 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/k8scat/mirror-git-go/pkg/credential"
+	"github.com/k8scat/mirror-git-go/pkg/filter"
+	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/mapping"
+	"github.com/k8scat/mirror-git-go/pkg/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration file of mirror-git.
+// Values from the file take precedence over the environment.
+type Config struct {
+	// Providers holds named provider settings. Without Type, the name is the provider type, e.g. gitlab or github.
+	Providers map[string]Provider `yaml:"providers"`
+	// Filters selects the source repositories to mirror, for jobs without filters of their own
+	Filters filter.Options `yaml:"filters"`
+	// Jobs mirror a source provider to target providers. Without jobs, the -source and -target flags are used.
+	Jobs []Job `yaml:"jobs"`
+}
+
+// Provider configures the hosts of a self-hosted provider instance and its target namespace
+type Provider struct {
+	// Type is the provider type, the name of the provider by default
+	Type string `yaml:"type"`
+	// BaseURL is the web URL of the instance, the API URLs are derived from it
+	BaseURL string `yaml:"base_url"`
+	// APIURL overrides the derived REST API URL
+	APIURL string `yaml:"api_url"`
+	// GraphQLURL overrides the derived GraphQL API URL, only used by GitHub
+	GraphQLURL string `yaml:"graphql_url"`
+	// Namespace is the organization or group that target repositories are created in
+	Namespace string `yaml:"namespace"`
+	// Group limits a GitLab source to the projects of a group and its subgroups
+	Group string `yaml:"group"`
+	// Organization is the Azure DevOps organization, or the organization a Gitee or Gitea provider uses
+	Organization string `yaml:"organization"`
+	// EnterpriseID is the enterprise of an Enterprise Gitee source
+	EnterpriseID string `yaml:"enterprise_id"`
+	// File is the file a urllist source reads repository URLs from
+	File string `yaml:"file"`
+	// Dir is the directory a local source scans for repositories
+	Dir string `yaml:"dir"`
+	// RPS caps the API requests per second, overriding the -rps flag
+	RPS float64 `yaml:"rps"`
+	// Credentials override the credentials from the environment
+	Credentials Credentials `yaml:"credentials"`
+	// SSH makes clones and pushes use SSH instead of HTTP(S) with the access token,
+	// the token is still used for the API
+	SSH *git.SSH `yaml:"ssh"`
+}
+
+// Credentials reference the credentials of a provider without holding the secret itself.
+// At most one source of the access token may be set.
+type Credentials struct {
+	Username string `yaml:"username"`
+	// TokenEnv is the environment variable holding the access token,
+	// or if it is not set TokenEnv_FILE naming a file holding it
+	TokenEnv string `yaml:"token_env"`
+	// TokenFile holds the access token, e.g. a mounted Docker or Kubernetes secret
+	TokenFile string `yaml:"token_file"`
+	// TokenCommand is run with the shell and prints the access token
+	TokenCommand string `yaml:"token_command"`
+	// GitCredential asks the git credential helpers for the username and access token
+	GitCredential bool `yaml:"git_credential"`
+}
+
+// Resolve returns the username and access token referenced by the credentials, both empty if
+// none are referenced. The git credential helpers are asked for the credentials of baseURL.
+func (c Credentials) Resolve(ctx context.Context, baseURL string) (username, token string, err error) {
+	switch {
+	case c.TokenEnv != "":
+		token, err = credential.Env(c.TokenEnv)
+		if err == nil && token == "" {
+			err = fmt.Errorf("environment variable %s is not set", c.TokenEnv)
+		}
+	case c.TokenFile != "":
+		token, err = credential.File(c.TokenFile)
+	case c.TokenCommand != "":
+		token, err = credential.Command(ctx, c.TokenCommand)
+	case c.GitCredential:
+		return credential.Fill(ctx, baseURL, c.Username)
+	}
+	return c.Username, token, err
+}
+
+// Job mirrors the repositories of a source provider to target providers
+type Job struct {
+	Name string `yaml:"name"`
+	// Source and Targets are provider names. Each repo is cloned once and pushed to all targets.
+	Source  string   `yaml:"source"`
+	Targets []string `yaml:"targets"`
+	// Filters replace the top level filters of the config for this job
+	Filters *filter.Options `yaml:"filters"`
+	Mapping Mapping         `yaml:"mapping"`
+	// Concurrency is the number of repos mirrored at once, the -concurrency flag by default
+	Concurrency int `yaml:"concurrency"`
+	// Schedule is a cron expression or @every interval the job runs at with -daemon
+	Schedule string `yaml:"schedule"`
+}
+
+// Mapping configures how source paths map to target paths
+type Mapping struct {
+	// Mode is flat, encode or preserve, the -namespace-mode flag by default
+	Mode string `yaml:"mode"`
+	// Separator joins namespace segments in encode mode
+	Separator string `yaml:"separator"`
+}
+
+// Load reads a YAML (or JSON) configuration file. Unknown keys are an error.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config failed: %w", err)
+	}
+
+	cfg := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse config %s failed: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ProviderType returns the type of a named provider
+func (c *Config) ProviderType(name string) string {
+	if p, ok := c.Providers[name]; ok && p.Type != "" {
+		return p.Type
+	}
+	return name
+}
+
+// Validate checks the config, reporting every problem with the path of the offending key
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := c.Providers[name]
+		path := "providers." + name
+		typ := c.ProviderType(name)
+		if !slices.Contains(git.SourceTypes, typ) && !slices.Contains(git.TargetTypes, typ) {
+			fail(path+".type", "unknown provider type %q, must be one of %s", typ, strings.Join(providerTypes(), ", "))
+		}
+		for _, u := range []struct{ key, value string }{{"base_url", p.BaseURL}, {"api_url", p.APIURL}, {"graphql_url", p.GraphQLURL}} {
+			if u.value == "" {
+				continue
+			}
+			if parsed, err := url.Parse(u.value); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				fail(path+"."+u.key, "%q is not an absolute URL", u.value)
+			}
+		}
+		if p.RPS < 0 {
+			fail(path+".rps", "must not be negative")
+		}
+		for _, k := range typeKeys {
+			if k.value(p) != "" && !slices.Contains(k.types, typ) {
+				fail(path+"."+k.key, "provider type %q does not use %s", typ, k.key)
+			}
+		}
+		creds := p.Credentials
+		sources := 0
+		for _, set := range []bool{creds.TokenEnv != "", creds.TokenFile != "", creds.TokenCommand != "", creds.GitCredential} {
+			if set {
+				sources++
+			}
+		}
+		if sources > 1 {
+			fail(path+".credentials", "only one of token_env, token_file, token_command and git_credential may be set")
+		}
+		if creds.TokenEnv != "" && os.Getenv(creds.TokenEnv) == "" && os.Getenv(creds.TokenEnv+"_FILE") == "" {
+			fail(path+".credentials.token_env", "environment variable %s is not set", creds.TokenEnv)
+		}
+		if creds.TokenFile != "" {
+			if _, err := os.Stat(creds.TokenFile); err != nil {
+				fail(path+".credentials.token_file", "%v", err)
+			}
+		}
+		if p.SSH != nil {
+			if typ == git.Local || typ == git.URLList {
+				fail(path+".ssh", "provider type %q does not support ssh", typ)
+			}
+			if p.SSH.Port < 0 || p.SSH.Port > 65535 {
+				fail(path+".ssh.port", "%d is not a valid port", p.SSH.Port)
+			}
+			for _, f := range []struct{ key, file string }{{"key_file", p.SSH.KeyFile}, {"known_hosts_file", p.SSH.KnownHostsFile}} {
+				if f.file == "" {
+					continue
+				}
+				if _, err := os.Stat(f.file); err != nil {
+					fail(path+".ssh."+f.key, "%v", err)
+				}
+			}
+		}
+	}
+
+	if _, err := filter.New(c.Filters); err != nil {
+		fail("filters", "%v", err)
+	}
+
+	jobNames := make(map[string]int)
+	for i, job := range c.Jobs {
+		path := fmt.Sprintf("jobs[%d]", i)
+		if job.Name == "" {
+			fail(path+".name", "is required")
+		} else if first, ok := jobNames[job.Name]; ok {
+			fail(path+".name", "%q is already used by jobs[%d]", job.Name, first)
+		} else {
+			jobNames[job.Name] = i
+			path = "jobs." + job.Name
+		}
+
+		if job.Source == "" {
+			fail(path+".source", "is required")
+		} else if typ := c.ProviderType(job.Source); !slices.Contains(git.SourceTypes, typ) {
+			fail(path+".source", "provider %q of type %q cannot be a source", job.Source, typ)
+		}
+		if len(job.Targets) == 0 {
+			fail(path+".targets", "at least one target is required")
+		}
+		for j, target := range job.Targets {
+			if typ := c.ProviderType(target); !slices.Contains(git.TargetTypes, typ) {
+				fail(fmt.Sprintf("%s.targets[%d]", path, j), "provider %q of type %q cannot be a target", target, typ)
+			}
+			if slices.Index(job.Targets, target) < j {
+				fail(fmt.Sprintf("%s.targets[%d]", path, j), "provider %q is listed twice", target)
+			}
+			// Repos are cloned into the work dir for a local target, not pushed from a bare clone
+			if c.ProviderType(target) == git.Local && len(job.Targets) > 1 {
+				fail(fmt.Sprintf("%s.targets[%d]", path, j), "a local target cannot be combined with other targets")
+			}
+		}
+
+		if job.Filters != nil {
+			if _, err := filter.New(*job.Filters); err != nil {
+				fail(path+".filters", "%v", err)
+			}
+		}
+		if job.Mapping.Mode != "" {
+			if _, err := mapping.NewMapper(job.Mapping.Mode); err != nil {
+				fail(path+".mapping.mode", "%v", err)
+			}
+		}
+		if job.Concurrency < 0 {
+			fail(path+".concurrency", "must not be negative")
+		}
+		if job.Schedule != "" {
+			if _, err := schedule.Parse(job.Schedule); err != nil {
+				fail(path+".schedule", "%v", err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// typeKeys are the provider keys only some provider types use
+var typeKeys = []struct {
+	key   string
+	types []string
+	value func(Provider) string
+}{
+	{"namespace", []string{git.GitHub, git.GitLab, git.Gitee, git.Gitea, git.Bitbucket, git.AzureDevOps}, func(p Provider) string { return p.Namespace }},
+	{"group", []string{git.GitLab}, func(p Provider) string { return p.Group }},
+	{"organization", []string{git.Gitee, git.Gitea, git.AzureDevOps}, func(p Provider) string { return p.Organization }},
+	{"enterprise_id", []string{git.EGiteeV8}, func(p Provider) string { return p.EnterpriseID }},
+	{"file", []string{git.URLList}, func(p Provider) string { return p.File }},
+	{"dir", []string{git.Local}, func(p Provider) string { return p.Dir }},
+}
+
+func providerTypes() []string {
+	types := slices.Clone(git.SourceTypes)
+	for _, t := range git.TargetTypes {
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k8scat/mirror-git-go/pkg/git"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mirror.yaml")
+	err := os.WriteFile(file, []byte(`
+providers:
+  gitlab:
+    base_url: https://gitlab.example.com
+  github:
+    base_url: https://ghes.example.com
+    api_url: https://ghes-api.example.com/api/v3
+    rps: 2.5
+    ssh:
+      port: 2222
+      key_file: /etc/mirror/id_ed25519
+filters:
+  include:
+    - platform/**
+  exclude_archived: true
+  max_size: 500MB
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Providers["gitlab"].BaseURL; got != "https://gitlab.example.com" {
+		t.Errorf("gitlab base_url = %q", got)
+	}
+	if got := cfg.Providers["github"].APIURL; got != "https://ghes-api.example.com/api/v3" {
+		t.Errorf("github api_url = %q", got)
+	}
+	if got := cfg.Providers["github"].RPS; got != 2.5 {
+		t.Errorf("github rps = %v", got)
+	}
+	if ssh := cfg.Providers["github"].SSH; ssh == nil || ssh.Port != 2222 || ssh.KeyFile != "/etc/mirror/id_ed25519" {
+		t.Errorf("github ssh = %+v", ssh)
+	}
+	if cfg.Providers["gitlab"].SSH != nil {
+		t.Errorf("gitlab ssh set without an ssh section")
+	}
+	if f := cfg.Filters; len(f.Include) != 1 || f.Include[0] != "platform/**" || !f.ExcludeArchived || f.MaxSize != "500MB" {
+		t.Errorf("filters = %+v", f)
+	}
+}
+
+func TestLoadJobs(t *testing.T) {
+	t.Setenv("CORP_GITLAB_TOKEN", "secret")
+	file := filepath.Join(t.TempDir(), "mirror.yaml")
+	err := os.WriteFile(file, []byte(`
+providers:
+  corp:
+    type: gitlab
+    base_url: https://gitlab.example.com
+    credentials:
+      username: mirror-bot
+      token_env: CORP_GITLAB_TOKEN
+  gitee:
+    namespace: acme
+  other:
+    type: gitlab
+    group: platform/backend
+  list:
+    type: urllist
+    file: /etc/mirror/repos.txt
+jobs:
+  - name: nightly
+    source: corp
+    targets: [gitee]
+    filters:
+      exclude: ["sandbox/**"]
+    mapping:
+      mode: encode
+    concurrency: 10
+    schedule: "0 3 * * *"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.ProviderType("corp"); got != "gitlab" {
+		t.Errorf("type of corp = %q, want gitlab", got)
+	}
+	if got := cfg.ProviderType("gitee"); got != "gitee" {
+		t.Errorf("type of gitee = %q, want the name", got)
+	}
+	if username, token, err := cfg.Providers["corp"].Credentials.Resolve(context.Background(), ""); err != nil || username != "mirror-bot" || token != "secret" {
+		t.Errorf("credentials of corp = %q, %q, %v", username, token, err)
+	}
+	if got := cfg.Providers["other"].Group; got != "platform/backend" {
+		t.Errorf("group of other = %q", got)
+	}
+	if got := cfg.Providers["list"].File; got != "/etc/mirror/repos.txt" {
+		t.Errorf("file of list = %q", got)
+	}
+	job := cfg.Jobs[0]
+	if job.Source != "corp" || len(job.Targets) != 1 || job.Mapping.Mode != "encode" || job.Concurrency != 10 || job.Filters.Exclude[0] != "sandbox/**" {
+		t.Errorf("unexpected job %+v", job)
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MIRROR_GIT_TEST_TOKEN_FILE", file)
+
+	tests := []struct {
+		c       Credentials
+		want    string
+		wantErr string
+	}{
+		{Credentials{}, "", ""},
+		{Credentials{TokenEnv: "MIRROR_GIT_TEST_TOKEN"}, "from-file", ""},
+		{Credentials{TokenFile: file}, "from-file", ""},
+		{Credentials{TokenCommand: "echo from-command"}, "from-command", ""},
+		{Credentials{TokenEnv: "MIRROR_GIT_TEST_UNSET"}, "", "environment variable MIRROR_GIT_TEST_UNSET is not set"},
+		{Credentials{TokenCommand: "exit 1"}, "", "token command failed"},
+	}
+	for _, tt := range tests {
+		_, token, err := tt.c.Resolve(context.Background(), "https://git.example.com")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Resolve(%+v) = %v, want error %q", tt.c, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || token != tt.want {
+			t.Errorf("Resolve(%+v) = %q, %v, want %q", tt.c, token, err, tt.want)
+		}
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "mirror.yaml")
+	if err := os.WriteFile(file, []byte("jobs:\n  - name: a\n    target: gitee\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(file); err == nil || !strings.Contains(err.Error(), "field target not found") {
+		t.Errorf("Load() = %v, want unknown field error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &Config{
+		Providers: map[string]Provider{
+			"corp":  {Type: "gitlabb"},
+			"hub":   {Type: "github", BaseURL: "ghes.example.com", APIURL: "api"},
+			"list":  {Type: "urllist", Credentials: Credentials{TokenEnv: "MIRROR_GIT_TEST_UNSET"}, SSH: &git.SSH{}},
+			"lab":   {Type: "gitlab", SSH: &git.SSH{Port: 70000, KeyFile: "/nonexistent/id_ed25519"}},
+			"tea":   {Type: "gitea", Organization: "acme", Credentials: Credentials{TokenFile: "/nonexistent/token", TokenCommand: "vault read"}},
+			"ent":   {Type: "e_gitee_v8", Namespace: "acme", Group: "platform"},
+			"gitee": {},
+		},
+		Jobs: []Job{
+			{Name: "a", Source: "hub", Targets: []string{"list", "gitee", "gitee"}, Schedule: "every day"},
+			{Name: "a", Targets: nil, Mapping: Mapping{Mode: "nested"}, Concurrency: -1},
+			{Name: "b", Source: "hub", Targets: []string{"local", "gitee"}},
+		},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() succeeded, want errors")
+	}
+	for _, want := range []string{
+		`providers.corp.type: unknown provider type "gitlabb"`,
+		`providers.hub.base_url: "ghes.example.com" is not an absolute URL`,
+		`providers.hub.api_url: "api" is not an absolute URL`,
+		`providers.list.credentials.token_env: environment variable MIRROR_GIT_TEST_UNSET is not set`,
+		`providers.list.ssh: provider type "urllist" does not support ssh`,
+		`providers.lab.ssh.port: 70000 is not a valid port`,
+		`providers.tea.credentials: only one of token_env, token_file, token_command and git_credential may be set`,
+		`providers.tea.credentials.token_file: stat /nonexistent/token: no such file or directory`,
+		`providers.lab.ssh.key_file: stat /nonexistent/id_ed25519: no such file or directory`,
+		`jobs.a.targets[0]: provider "list" of type "urllist" cannot be a target`,
+		`jobs.a.targets[2]: provider "gitee" is listed twice`,
+		`jobs.b.targets[0]: a local target cannot be combined with other targets`,
+		`jobs.a.schedule: invalid schedule "every day"`,
+		`jobs[1].name: "a" is already used by jobs[0]`,
+		`jobs[1].source: is required`,
+		`jobs[1].targets: at least one target is required`,
+		`jobs[1].mapping.mode: invalid namespace mapping mode "nested"`,
+		`jobs[1].concurrency: must not be negative`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing error %q in:\n%v", want, err)
+		}
+	}
+	// URL keys are reported in a fixed order
+	if strings.Index(err.Error(), "providers.hub.base_url") > strings.Index(err.Error(), "providers.hub.api_url") {
+		t.Errorf("api_url reported before base_url:\n%v", err)
+	}
+	if strings.Contains(err.Error(), "providers.tea.organization") {
+		t.Errorf("organization of a gitea provider rejected:\n%v", err)
+	}
+}
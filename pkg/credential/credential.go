@@ -0,0 +1,106 @@
+// Package credential resolves access tokens from the environment, files, commands
+// and git credential helpers
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Env returns the value of the environment variable name or, if it is not set, the contents of
+// the file named by name_FILE as mounted for Docker and Kubernetes secrets. Neither being set
+// is not an error.
+func Env(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		token, err := File(path)
+		if err != nil {
+			return "", fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return token, nil
+	}
+	return "", nil
+}
+
+// File returns the token in a file, without surrounding whitespace
+func File(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read token file failed: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// Command runs command with the shell and returns the token it prints
+func Command(ctx context.Context, command string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("token command printed no token")
+	}
+	return token, nil
+}
+
+// Fill asks the git credential helpers for the credentials of the host of repoURL using
+// git credential fill. A username narrows down which credentials are returned.
+func Fill(ctx context.Context, repoURL, username string) (string, string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid url %q for git credential", repoURL)
+	}
+
+	var input strings.Builder
+	fmt.Fprintf(&input, "protocol=%s\nhost=%s\n", u.Scheme, u.Host)
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		fmt.Fprintf(&input, "path=%s\n", path)
+	}
+	if username != "" {
+		fmt.Fprintf(&input, "username=%s\n", username)
+	}
+	input.WriteString("\n")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	// Fail instead of asking on the terminal when no helper has the credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	cmd.Stdin = strings.NewReader(input.String())
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("git credential fill failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var password string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+	if password == "" {
+		return "", "", fmt.Errorf("git credential returned no password for %s", u.Host)
+	}
+	return username, password, nil
+}
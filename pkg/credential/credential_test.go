@@ -0,0 +1,70 @@
+package credential
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("MIRROR_TEST_TOKEN_FILE", file)
+	if got, err := Env("MIRROR_TEST_TOKEN"); err != nil || got != "from-file" {
+		t.Errorf("Env() = %q, %v, want the token of the file", got, err)
+	}
+	t.Setenv("MIRROR_TEST_TOKEN", "from-env")
+	if got, err := Env("MIRROR_TEST_TOKEN"); err != nil || got != "from-env" {
+		t.Errorf("Env() = %q, %v, want the variable to take precedence", got, err)
+	}
+
+	t.Setenv("MIRROR_TEST_MISSING_FILE", filepath.Join(t.TempDir(), "missing"))
+	if _, err := Env("MIRROR_TEST_MISSING"); err == nil || !strings.Contains(err.Error(), "MIRROR_TEST_MISSING_FILE") {
+		t.Errorf("Env() with a missing file = %v, want an error naming the variable", err)
+	}
+	if got, err := Env("MIRROR_TEST_UNSET"); err != nil || got != "" {
+		t.Errorf("Env() of an unset variable = %q, %v", got, err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if got, err := Command(context.Background(), "echo '  tok3n  '"); err != nil || got != "tok3n" {
+		t.Errorf("Command() = %q, %v, want tok3n", got, err)
+	}
+	if _, err := Command(context.Background(), "echo vault sealed >&2; exit 2"); err == nil || !strings.Contains(err.Error(), "vault sealed") {
+		t.Errorf("Command() = %v, want the stderr in the error", err)
+	}
+	if _, err := Command(context.Background(), "true"); err == nil {
+		t.Errorf("Command() without output succeeded")
+	}
+}
+
+func TestFill(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "credential.https://git.example.com.helper")
+	t.Setenv("GIT_CONFIG_VALUE_0", `!f() { test "$1" = get && echo username=bot && echo password=s3cr3t; }; f`)
+
+	username, password, err := Fill(context.Background(), "https://git.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "bot" || password != "s3cr3t" {
+		t.Errorf("Fill() = %q, %q, want bot, s3cr3t", username, password)
+	}
+
+	// No helper is configured for other hosts, and git must not prompt for them
+	if _, _, err := Fill(context.Background(), "https://other.example.com", "bot"); err == nil {
+		t.Errorf("Fill() for a host without credentials succeeded")
+	}
+}
@@ -1,6 +1,7 @@
 package e_gitee_v8
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,20 +9,38 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/k8scat/mirror-git-go/pkg/git"
 	"github.com/k8scat/mirror-git-go/pkg/types"
 	"github.com/tidwall/gjson"
 )
 
 var _ types.SourceGit = &EnterpriseGiteeV8{}
+var _ types.HTTPGit = &EnterpriseGiteeV8{}
+var _ types.SSHGit = &EnterpriseGiteeV8{}
+var _ types.CredentialGit = &EnterpriseGiteeV8{}
 
 type EnterpriseGiteeV8 struct {
 	EnterpriseId string
 	Username     string
 	AccessToken  string
+	// BaseURL is the web URL of the instance, used for clone URLs
+	BaseURL string
+	BaseAPI string
+
+	client *http.Client
+	ssh    *git.SSH
 }
 
+const (
+	// DefaultBaseURL is the URL of gitee.com
+	DefaultBaseURL = "https://gitee.com"
+	// DefaultBaseAPI is the enterprise API of gitee.com
+	DefaultBaseAPI = "https://api.gitee.com"
+)
+
 func (g *EnterpriseGiteeV8) Name() string {
 	return "e_gitee_v8"
 }
@@ -31,14 +50,34 @@ func NewEnterpriseGiteeV8(enterpriseId, accessToken, username string) *Enterpris
 		EnterpriseId: enterpriseId,
 		Username:     username,
 		AccessToken:  accessToken,
+		BaseURL:      DefaultBaseURL,
+		BaseAPI:      DefaultBaseAPI,
 	}
 }
 
 func NewEnterpriseGiteeV8FromEnv() *EnterpriseGiteeV8 {
-	return &EnterpriseGiteeV8{
-		EnterpriseId: os.Getenv("E_GITEE_V8_ENTERPRISE_ID"),
-		Username:     os.Getenv("E_GITEE_V8_USERNAME"),
-		AccessToken:  os.Getenv("E_GITEE_V8_ACCESS_TOKEN"),
+	g := NewEnterpriseGiteeV8(
+		os.Getenv("E_GITEE_V8_ENTERPRISE_ID"),
+		os.Getenv("E_GITEE_V8_ACCESS_TOKEN"),
+		os.Getenv("E_GITEE_V8_USERNAME"),
+	)
+	if baseURL := os.Getenv("E_GITEE_V8_BASE_URL"); baseURL != "" {
+		g.SetBaseURL(baseURL)
+	}
+	if apiURL := os.Getenv("E_GITEE_V8_API_URL"); apiURL != "" {
+		g.BaseAPI = strings.TrimSuffix(apiURL, "/")
+	}
+	return g
+}
+
+// SetBaseURL points the client at a Gitee Enterprise instance.
+// gitee.com serves the API from its own host, private deployments serve it under /api.
+func (g *EnterpriseGiteeV8) SetBaseURL(baseURL string) {
+	g.BaseURL = strings.TrimSuffix(baseURL, "/")
+	if g.BaseURL == DefaultBaseURL {
+		g.BaseAPI = DefaultBaseAPI
+	} else {
+		g.BaseAPI = g.BaseURL + "/api"
 	}
 }
 
@@ -110,10 +149,10 @@ type Repo struct {
 	WikiEnabledWithContent  bool        `json:"wiki_enabled_with_content"`
 }
 
-func (g *EnterpriseGiteeV8) listRepos(page, perPage int) ([]types.Repo, error) {
-	api := "https://api.gitee.com/enterprises/" + g.EnterpriseId + "/projects"
+func (g *EnterpriseGiteeV8) listRepos(ctx context.Context, page, perPage int) ([]types.Repo, error) {
+	api := g.BaseAPI + "/enterprises/" + g.EnterpriseId + "/projects"
 
-	req, err := http.NewRequest(http.MethodGet, api, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -127,14 +166,14 @@ func (g *EnterpriseGiteeV8) listRepos(page, perPage int) ([]types.Repo, error) {
 	queries.Set("page", fmt.Sprintf("%d", page))
 	req.URL.RawQuery = queries.Encode()
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := g.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
+		return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("API request failed with status code: %d", resp.StatusCode))
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -164,12 +203,12 @@ func (g *EnterpriseGiteeV8) listRepos(page, perPage int) ([]types.Repo, error) {
 	return result, nil
 }
 
-func (g *EnterpriseGiteeV8) ListRepos() ([]types.Repo, error) {
+func (g *EnterpriseGiteeV8) ListRepos(ctx context.Context) ([]types.Repo, error) {
 	allRepos := make([]types.Repo, 0)
 	page := 1
 	perPage := 100
 	for {
-		repos, err := g.listRepos(page, perPage)
+		repos, err := g.listRepos(ctx, page, perPage)
 		if err != nil {
 			return nil, err
 		}
@@ -188,5 +227,43 @@ func (g *EnterpriseGiteeV8) ListRepos() ([]types.Repo, error) {
 
 // GetSourceRepoAddr implements types.SourceGit.
 func (g *EnterpriseGiteeV8) GetSourceRepoAddr(pathWithNamespace string) string {
-	return fmt.Sprintf("https://%s:%s@gitee.com/%s.git", g.Username, g.AccessToken, pathWithNamespace)
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, pathWithNamespace)
+}
+
+// SetTransport implements types.HTTPGit.
+func (g *EnterpriseGiteeV8) SetTransport(rt http.RoundTripper) {
+	g.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (g *EnterpriseGiteeV8) SetSSH(ssh *git.SSH) {
+	g.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (g *EnterpriseGiteeV8) SSHConfig() *git.SSH {
+	return g.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (g *EnterpriseGiteeV8) Credentials() (string, string) {
+	return g.Username, g.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (g *EnterpriseGiteeV8) SetCredentials(username, token string) {
+	g.Username, g.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (g *EnterpriseGiteeV8) CredentialURL() string {
+	return g.BaseURL
+}
+
+// httpClient returns the client of the API requests
+func (g *EnterpriseGiteeV8) httpClient() *http.Client {
+	if g.client == nil {
+		return &http.Client{Timeout: 60 * time.Second}
+	}
+	return g.client
 }
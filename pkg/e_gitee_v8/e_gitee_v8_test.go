@@ -1,6 +1,7 @@
 package e_gitee_v8
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -8,7 +9,7 @@ import (
 func TestListRepos(t *testing.T) {
 	g := NewEnterpriseGiteeV8FromEnv()
 
-	repos, err := g.ListRepos()
+	repos, err := g.ListRepos(context.Background())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,216 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+// Visibilities of repositories to mirror
+const (
+	VisibilityAll     = "all"
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// regexPrefix marks a pattern as a regular expression instead of a glob
+const regexPrefix = "re:"
+
+// Options configures which source repositories are mirrored
+type Options struct {
+	// Include lists the patterns of the paths with namespace to mirror, all repositories when empty.
+	// Patterns are globs where * matches within a path segment and ** across segments,
+	// or regular expressions when prefixed with re:
+	Include []string `yaml:"include"`
+	// Exclude lists the patterns of the paths with namespace to skip, it takes precedence over Include
+	Exclude []string `yaml:"exclude"`
+	// Visibility is one of all, public or private
+	Visibility      string `yaml:"visibility"`
+	ExcludeForks    bool   `yaml:"exclude_forks"`
+	ExcludeArchived bool   `yaml:"exclude_archived"`
+	// MaxSize skips larger repositories, e.g. 500MB or 2G
+	MaxSize string `yaml:"max_size"`
+	// MaxPushAge skips repositories not pushed to for longer, e.g. 90d or 720h
+	MaxPushAge string `yaml:"max_push_age"`
+}
+
+// Filter decides which source repositories are mirrored.
+// Details a provider does not report, like the size of a repository, never cause a skip.
+type Filter struct {
+	include         []*regexp.Regexp
+	exclude         []*regexp.Regexp
+	visibility      string
+	excludeForks    bool
+	excludeArchived bool
+	maxSize         int64
+	maxPushAge      time.Duration
+	now             func() time.Time
+}
+
+func New(opts Options) (*Filter, error) {
+	f := &Filter{
+		visibility:      opts.Visibility,
+		excludeForks:    opts.ExcludeForks,
+		excludeArchived: opts.ExcludeArchived,
+		now:             time.Now,
+	}
+
+	for _, p := range opts.Include {
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern %q: %w", p, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, p := range opts.Exclude {
+		re, err := compilePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", p, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+
+	switch opts.Visibility {
+	case "":
+		f.visibility = VisibilityAll
+	case VisibilityAll, VisibilityPublic, VisibilityPrivate:
+	default:
+		return nil, fmt.Errorf("invalid visibility %q, must be one of %s, %s, %s", opts.Visibility, VisibilityAll, VisibilityPublic, VisibilityPrivate)
+	}
+
+	if opts.MaxSize != "" {
+		size, err := ParseSize(opts.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		f.maxSize = size
+	}
+	if opts.MaxPushAge != "" {
+		age, err := ParseAge(opts.MaxPushAge)
+		if err != nil {
+			return nil, err
+		}
+		f.maxPushAge = age
+	}
+	return f, nil
+}
+
+// Match reports whether repo is mirrored, and if not, the reason it is skipped
+func (f *Filter) Match(repo types.Repo) (bool, string) {
+	name := repo.GetPathWithNamespace()
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false, "not matched by any include pattern"
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false, "matched exclude pattern " + re.String()
+		}
+	}
+
+	switch {
+	case f.visibility == VisibilityPublic && repo.GetPrivate():
+		return false, "private"
+	case f.visibility == VisibilityPrivate && !repo.GetPrivate():
+		return false, "public"
+	}
+
+	meta, ok := repo.(types.RepoMetadata)
+	if !ok {
+		return true, ""
+	}
+	if f.excludeForks && meta.IsFork() {
+		return false, "fork"
+	}
+	if f.excludeArchived && meta.IsArchived() {
+		return false, "archived"
+	}
+	if f.maxSize > 0 && meta.GetSize() > f.maxSize {
+		return false, fmt.Sprintf("size %d bytes exceeds %d bytes", meta.GetSize(), f.maxSize)
+	}
+	if pushedAt := meta.GetPushedAt(); f.maxPushAge > 0 && !pushedAt.IsZero() && f.now().Sub(pushedAt) > f.maxPushAge {
+		return false, "last pushed at " + pushedAt.Format(time.RFC3339)
+	}
+	return true, ""
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern compiles a glob, or a regular expression prefixed with re:
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(p, regexPrefix); ok {
+		return regexp.Compile(expr)
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"B":  1,
+	"K":  1 << 10,
+	"KB": 1 << 10,
+	"M":  1 << 20,
+	"MB": 1 << 20,
+	"G":  1 << 30,
+	"GB": 1 << 30,
+}
+
+// ParseSize parses a size in bytes with an optional binary unit, e.g. 512K, 500MB or 2G
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	num := strings.TrimRight(s, "KMGB")
+	unit, ok := sizeUnits[s[len(num):]]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// ParseAge parses a duration that, besides the units of time.ParseDuration, accepts days, e.g. 90d
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", s, err)
+	}
+	return d, nil
+}
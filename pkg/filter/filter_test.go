@@ -0,0 +1,73 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+func TestMatch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	f, err := New(Options{
+		Include:         []string{"platform/**", "re:^tools/(cli|sdk)$"},
+		Exclude:         []string{"platform/*-legacy"},
+		Visibility:      VisibilityPrivate,
+		ExcludeForks:    true,
+		ExcludeArchived: true,
+		MaxSize:         "1GB",
+		MaxPushAge:      "90d",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.now = func() time.Time { return now }
+
+	tests := []struct {
+		repo types.Repo
+		want bool
+	}{
+		{&types.RepoImpl{PathWithNamespace: "platform/api", Private: true}, true},
+		{&types.RepoImpl{PathWithNamespace: "platform/infra/terraform", Private: true}, true},
+		{&types.RepoImpl{PathWithNamespace: "tools/cli", Private: true}, true},
+		{&types.RepoImpl{PathWithNamespace: "tools/web", Private: true}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/api-legacy", Private: true}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/site", Private: false}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/fork", Private: true, Fork: true}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/old", Private: true, Archived: true}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/big", Private: true, Size: 2 << 30}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/stale", Private: true, PushedAt: now.AddDate(0, -6, 0)}, false},
+		{&types.RepoImpl{PathWithNamespace: "platform/fresh", Private: true, PushedAt: now.AddDate(0, 0, -1)}, true},
+	}
+	for _, tt := range tests {
+		got, reason := f.Match(tt.repo)
+		if got != tt.want {
+			t.Errorf("Match(%s) = %v (%s), want %v", tt.repo.GetPathWithNamespace(), got, reason, tt.want)
+		}
+		if !got && reason == "" {
+			t.Errorf("Match(%s) gave no reason for the skip", tt.repo.GetPathWithNamespace())
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	for _, opts := range []Options{
+		{Include: []string{"re:("}},
+		{Visibility: "internal"},
+		{MaxSize: "10XB"},
+		{MaxPushAge: "soon"},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded, want error", opts)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{"100": 100, "512K": 512 << 10, "1.5mb": 3 << 19, "2G": 2 << 30} {
+		got, err := ParseSize(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+}
@@ -1,9 +1,19 @@
 package git
 
 const (
-	EGiteeV8 = "e_gitee_v8"
-	GitLab   = "gitlab"
-	GitHub   = "github"
-	Local    = "local"
-	Gitee    = "gitee"
+	EGiteeV8    = "e_gitee_v8"
+	GitLab      = "gitlab"
+	GitHub      = "github"
+	Local       = "local"
+	Gitee       = "gitee"
+	Gitea       = "gitea"
+	Bitbucket   = "bitbucket"
+	AzureDevOps = "azuredevops"
+	URLList     = "urllist"
 )
+
+// SourceTypes are the provider types that can be mirrored from
+var SourceTypes = []string{EGiteeV8, GitLab, GitHub, Local, Gitee, Gitea, Bitbucket, AzureDevOps, URLList}
+
+// TargetTypes are the provider types that can be mirrored to
+var TargetTypes = []string{GitLab, GitHub, Local, Gitee, Gitea, Bitbucket, AzureDevOps}
@@ -0,0 +1,76 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// SSH configures cloning and pushing over SSH instead of HTTP(S) with a token
+type SSH struct {
+	// Host is the SSH host, by default the host of the web URL of the provider
+	Host string `yaml:"host"`
+	// Port is only needed if the host does not listen on 22
+	Port int `yaml:"port"`
+	// User defaults to git
+	User string `yaml:"user"`
+	// KeyFile is the private key, ssh falls back to its default keys and agent without one
+	KeyFile string `yaml:"key_file"`
+	// KnownHostsFile holds the host keys servers are checked against, ~/.ssh/known_hosts by default
+	KnownHostsFile string `yaml:"known_hosts_file"`
+}
+
+// RepoURL builds the SSH URL of a repository on the host of baseURL, e.g. git@github.com:org/repo.git.
+// The path is used verbatim, callers add the .git suffix if the host expects one.
+func (s *SSH) RepoURL(baseURL, path string) string {
+	host := s.Host
+	if host == "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return ""
+		}
+		host = u.Hostname()
+	}
+	user := s.User
+	if user == "" {
+		user = "git"
+	}
+	path = strings.Trim(path, "/")
+	// The scp-like syntax has no port
+	if s.Port != 0 && s.Port != 22 {
+		return fmt.Sprintf("ssh://%s@%s:%d/%s", user, host, s.Port, path)
+	}
+	return fmt.Sprintf("%s@%s:%s", user, host, path)
+}
+
+// Command returns the ssh command for GIT_SSH_COMMAND. It only accepts known host keys
+// and never prompts, so that a changed host key or missing key fails the command.
+func (s *SSH) Command() string {
+	args := []string{"ssh", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=yes"}
+	if s.KeyFile != "" {
+		args = append(args, "-o", "IdentitiesOnly=yes", "-i", shellQuote(s.KeyFile))
+	}
+	if s.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+shellQuote(s.KnownHostsFile))
+	}
+	return strings.Join(args, " ")
+}
+
+// Env returns the environment variables that make git use Command
+func (s *SSH) Env() []string {
+	return []string{"GIT_SSH_COMMAND=" + s.Command()}
+}
+
+// CloneURL builds the SSH URL of a repository when ssh is set,
+// and the HTTP(S) URL with the credentials embedded otherwise
+func CloneURL(ssh *SSH, baseURL, username, password, pathWithNamespace string) string {
+	if ssh != nil {
+		return ssh.RepoURL(baseURL, strings.Trim(pathWithNamespace, "/")+".git")
+	}
+	return RepoURL(baseURL, username, password, pathWithNamespace)
+}
+
+// shellQuote quotes s for the shell git runs GIT_SSH_COMMAND with
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
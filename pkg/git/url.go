@@ -0,0 +1,57 @@
+package git
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RepoURL builds the HTTP(S) URL of a repository on the host of baseURL,
+// with the credentials embedded as userinfo when username or password is set.
+func RepoURL(baseURL, username, password, pathWithNamespace string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	if username != "" || password != "" {
+		u.User = url.UserPassword(username, password)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.Trim(pathWithNamespace, "/") + ".git"
+	u.RawQuery = ""
+	return u.String()
+}
+
+// SplitCredentials removes the userinfo from an HTTP(S) repository URL, returning it separately
+// so that it can be passed to git without showing up in process listings or the git config.
+// Other URLs are returned unchanged.
+func SplitCredentials(repoURL string) (cleanURL, username, password string) {
+	u, err := url.Parse(repoURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User == nil {
+		return repoURL, "", ""
+	}
+	username = u.User.Username()
+	password, _ = u.User.Password()
+	u.User = nil
+	return u.String(), username, password
+}
+
+// BasicAuth encodes credentials for an HTTP Basic Authorization header
+func BasicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+// CredentialEnv returns environment variables that make git send the credentials
+// as an http.extraHeader, appended to any config already passed through GIT_CONFIG_COUNT.
+// Prompting is disabled so that git fails instead of waiting for a terminal.
+func CredentialEnv(username, password string) []string {
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	return []string{
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.extraHeader", n),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", n, BasicAuth(username, password)),
+		"GIT_TERMINAL_PROMPT=0",
+	}
+}
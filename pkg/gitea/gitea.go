@@ -0,0 +1,273 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/git"
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+var _ types.TargetGit = &Gitea{}
+var _ types.HTTPGit = &Gitea{}
+var _ types.SSHGit = &Gitea{}
+var _ types.CredentialGit = &Gitea{}
+var _ types.SourceGit = &Gitea{}
+
+// Gitea is a client for Gitea and Forgejo instances.
+type Gitea struct {
+	AccessToken string
+	Username    string
+	// BaseURL is the web URL of the instance, e.g. https://gitea.example.com
+	BaseURL string
+	BaseAPI string
+	// Org is the organization used to list and create repositories.
+	// When empty, the repositories of the user are used.
+	Org    string
+	client *http.Client
+	ssh    *git.SSH
+}
+
+// NewGitea creates a new Gitea client
+func NewGitea(baseURL, username, accessToken string) *Gitea {
+	g := &Gitea{
+		AccessToken: accessToken,
+		Username:    username,
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}
+	g.SetBaseURL(baseURL)
+	return g
+}
+
+// NewGiteaFromEnv creates a new Gitea client from environment variables
+func NewGiteaFromEnv() *Gitea {
+	g := NewGitea(os.Getenv("GITEA_BASE_URL"), os.Getenv("GITEA_USERNAME"), os.Getenv("GITEA_ACCESS_TOKEN"))
+	g.Org = os.Getenv("GITEA_ORG")
+	if apiURL := os.Getenv("GITEA_API_URL"); apiURL != "" {
+		g.BaseAPI = strings.TrimSuffix(apiURL, "/")
+	}
+	return g
+}
+
+// SetBaseURL points the client at an instance, deriving the API URL from it
+func (g *Gitea) SetBaseURL(baseURL string) {
+	g.BaseURL = strings.TrimSuffix(baseURL, "/")
+	g.BaseAPI = g.BaseURL + "/api/v1"
+}
+
+func (g *Gitea) Name() string {
+	return "gitea"
+}
+
+// Repo represents a repository returned by the Gitea API
+type Repo struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+	Fork        bool   `json:"fork"`
+	Archived    bool   `json:"archived"`
+	Size        int64  `json:"size"` // in KB
+	// UpdatedAt is the closest Gitea has to the time of the last push
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateRepoRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Private     bool   `json:"private"`
+}
+
+func (g *Gitea) owner() string {
+	if g.Org != "" {
+		return g.Org
+	}
+	return g.Username
+}
+
+func (g *Gitea) do(ctx context.Context, method, api string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, api, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+g.AccessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// ListRepos implements types.SourceGit.
+func (g *Gitea) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	allRepos := make([]types.Repo, 0)
+	page := 1
+	perPage := 50
+	for {
+		repos, err := g.listRepos(ctx, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		// Servers cap the page size with MAX_RESPONSE_ITEMS, possibly below perPage,
+		// so only an empty page ends the listing
+		if len(repos) == 0 {
+			break
+		}
+		for _, r := range repos {
+			allRepos = append(allRepos, &types.RepoImpl{
+				Path:              r.Name,
+				PathWithNamespace: r.FullName,
+				Desc:              r.Description,
+				Private:           r.Private,
+				Fork:              r.Fork,
+				Archived:          r.Archived,
+				Size:              r.Size * 1024,
+				PushedAt:          r.UpdatedAt,
+			})
+		}
+		page++
+	}
+	return allRepos, nil
+}
+
+// listRepos fetches a single page of repositories of the user or the configured organization.
+// https://docs.gitea.com/api/#tag/user/operation/userCurrentListRepos
+// https://docs.gitea.com/api/#tag/organization/operation/orgListRepos
+func (g *Gitea) listRepos(ctx context.Context, page, perPage int) ([]*Repo, error) {
+	queries := url.Values{}
+	queries.Set("page", fmt.Sprintf("%d", page))
+	queries.Set("limit", fmt.Sprintf("%d", perPage))
+
+	var api string
+	if g.Org != "" {
+		api = fmt.Sprintf("%s/orgs/%s/repos?%s", g.BaseAPI, url.PathEscape(g.Org), queries.Encode())
+	} else {
+		api = fmt.Sprintf("%s/user/repos?%s", g.BaseAPI, queries.Encode())
+	}
+
+	resp, err := g.do(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("list repos failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+
+	repos := make([]*Repo, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return repos, nil
+}
+
+// IsRepoExist implements types.TargetGit.
+func (g *Gitea) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
+	api := fmt.Sprintf("%s/repos/%s/%s", g.BaseAPI, url.PathEscape(g.owner()), url.PathEscape(repoName))
+	resp, err := g.do(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		respBody, _ := io.ReadAll(resp.Body)
+		return false, types.NewStatusError(resp.StatusCode, fmt.Errorf("error checking repo: status=%d, body=%s", resp.StatusCode, string(respBody)))
+	}
+}
+
+// CreateRepo implements types.TargetGit.
+func (g *Gitea) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
+	payload := CreateRepoRequest{
+		Name:        name,
+		Description: desc,
+		Private:     private,
+	}
+
+	api := g.BaseAPI + "/user/repos"
+	if g.Org != "" {
+		api = fmt.Sprintf("%s/orgs/%s/repos", g.BaseAPI, url.PathEscape(g.Org))
+	}
+
+	resp, err := g.do(ctx, http.MethodPost, api, payload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("create repo failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+	return nil
+}
+
+// GetTargetRepoAddr implements types.TargetGit.
+func (g *Gitea) GetTargetRepoAddr(ctx context.Context, path string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, g.owner()+"/"+path)
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+func (g *Gitea) GetSourceRepoAddr(pathWithNamespace string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, pathWithNamespace)
+}
+
+// SetTransport implements types.HTTPGit.
+func (g *Gitea) SetTransport(rt http.RoundTripper) {
+	g.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (g *Gitea) SetSSH(ssh *git.SSH) {
+	g.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (g *Gitea) SSHConfig() *git.SSH {
+	return g.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (g *Gitea) Credentials() (string, string) {
+	return g.Username, g.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (g *Gitea) SetCredentials(username, token string) {
+	g.Username, g.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (g *Gitea) CredentialURL() string {
+	return g.BaseURL
+}
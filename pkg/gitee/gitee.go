@@ -1,38 +1,95 @@
 package gitee
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/k8scat/mirror-git-go/pkg/git"
 	"github.com/k8scat/mirror-git-go/pkg/types"
 )
 
 var _ types.TargetGit = &Gitee{}
+var _ types.HTTPGit = &Gitee{}
+var _ types.SSHGit = &Gitee{}
+var _ types.CredentialGit = &Gitee{}
+var _ types.SourceGit = &Gitee{}
 
 type Gitee struct {
 	AccessToken string
 	Username    string
-	BaseAPI     string
-	client      *http.Client
-	Version     string
+	// BaseURL is the web URL of the instance, used for clone and push URLs
+	BaseURL string
+	BaseAPI string
+	client  *http.Client
+	ssh     *git.SSH
+	Version string
+	// Org limits ListRepos to the repositories of an organization.
+	// When empty, all repositories of the authenticated user are listed.
+	Org string
+	// Namespace is the organization that target repositories are checked, created
+	// and pushed in. When empty, the user's personal namespace is used.
+	Namespace string
 }
 
+// DefaultBaseURL is the URL of gitee.com
+const DefaultBaseURL = "https://gitee.com"
+
 func NewGiteeFromEnv() *Gitee {
 	g := &Gitee{
 		Username:    os.Getenv("GITEE_USERNAME"),
 		AccessToken: os.Getenv("GITEE_ACCESS_TOKEN"),
 		client:      &http.Client{Timeout: 60 * time.Second},
 		Version:     "v5",
+		Org:         os.Getenv("GITEE_ORG"),
+		Namespace:   os.Getenv("GITEE_NAMESPACE"),
+	}
+	g.SetBaseURL(DefaultBaseURL)
+	if baseURL := os.Getenv("GITEE_BASE_URL"); baseURL != "" {
+		g.SetBaseURL(baseURL)
+	}
+	if apiURL := os.Getenv("GITEE_API_URL"); apiURL != "" {
+		g.BaseAPI = strings.TrimSuffix(apiURL, "/")
 	}
-	g.BaseAPI = "https://gitee.com/api/" + g.Version
 	return g
 }
 
+// SetBaseURL points the client at a Gitee instance, deriving the API URL from it
+func (g *Gitee) SetBaseURL(baseURL string) {
+	g.BaseURL = strings.TrimSuffix(baseURL, "/")
+	g.BaseAPI = g.BaseURL + "/api/" + g.Version
+}
+
+// Repo represents a repository returned by the Gitee v5 API
+type Repo struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Path        string    `json:"path"`
+	FullName    string    `json:"full_name"`
+	Description string    `json:"description"`
+	Private     bool      `json:"private"`
+	Public      bool      `json:"public"`
+	Fork        bool      `json:"fork"`
+	PushedAt    time.Time `json:"pushed_at"`
+	Namespace   struct {
+		Path string `json:"path"`
+	} `json:"namespace"`
+}
+
+// PathWithNamespace returns the repository path prefixed with its namespace path.
+func (r *Repo) PathWithNamespace() string {
+	if r.Namespace.Path == "" {
+		return r.FullName
+	}
+	return r.Namespace.Path + "/" + r.Path
+}
+
 type CreateRepoRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
@@ -40,8 +97,18 @@ type CreateRepoRequest struct {
 	AccessToken string `json:"access_token"`
 }
 
+// owner returns the namespace that owns target repositories
+func (g *Gitee) owner() string {
+	if g.Namespace != "" {
+		return g.Namespace
+	}
+	return g.Username
+}
+
 // CreateRepo implements types.TargetGit.
-func (g *Gitee) CreateRepo(name string, desc string, private bool) error {
+// https://gitee.com/api/v5/swagger#/postV5UserRepos
+// https://gitee.com/api/v5/swagger#/postV5OrgsOrgRepos
+func (g *Gitee) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
 	payload := CreateRepoRequest{
 		Name:        name,
 		Description: desc,
@@ -50,11 +117,14 @@ func (g *Gitee) CreateRepo(name string, desc string, private bool) error {
 	}
 
 	url := g.BaseAPI + "/user/repos"
+	if g.Namespace != "" && g.Namespace != g.Username {
+		url = fmt.Sprintf("%s/orgs/%s/repos", g.BaseAPI, g.Namespace)
+	}
 	data, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(data)))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(data)))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -68,20 +138,20 @@ func (g *Gitee) CreateRepo(name string, desc string, private bool) error {
 	defer resp.Body.Close()
 	if resp.StatusCode != 201 {
 		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("create repo failed, status: %s, body: %s", resp.Status, string(respBody))
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("create repo failed, status: %s, body: %s", resp.Status, string(respBody)))
 	}
 	return nil
 }
 
 // GetTargetRepoAddr implements types.TargetGit.
-func (g *Gitee) GetTargetRepoAddr(path string) string {
-	return fmt.Sprintf("https://%s:%s@gitee.com/%s/%s.git", g.Username, g.AccessToken, g.Username, path)
+func (g *Gitee) GetTargetRepoAddr(ctx context.Context, path string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, g.owner()+"/"+path)
 }
 
 // IsRepoExist implements types.TargetGit.
-func (g *Gitee) IsRepoExist(repoName string) (bool, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s", g.BaseAPI, g.Username, repoName)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (g *Gitee) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", g.BaseAPI, g.owner(), repoName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -101,7 +171,7 @@ func (g *Gitee) IsRepoExist(repoName string) (bool, error) {
 		return false, nil
 	default:
 		respBody, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("error checking repo: status=%d, body=%s", resp.StatusCode, string(respBody))
+		return false, types.NewStatusError(resp.StatusCode, fmt.Errorf("error checking repo: status=%d, body=%s", resp.StatusCode, string(respBody)))
 	}
 }
 
@@ -109,3 +179,106 @@ func (g *Gitee) IsRepoExist(repoName string) (bool, error) {
 func (g *Gitee) Name() string {
 	return "gitee"
 }
+
+// GetSourceRepoAddr implements types.SourceGit.
+func (g *Gitee) GetSourceRepoAddr(pathWithNamespace string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, pathWithNamespace)
+}
+
+// ListRepos implements types.SourceGit.
+func (g *Gitee) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	allRepos := make([]types.Repo, 0)
+	page := 1
+	perPage := 100
+	for {
+		repos, err := g.listRepos(ctx, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			allRepos = append(allRepos, &types.RepoImpl{
+				Path:              r.Path,
+				PathWithNamespace: r.PathWithNamespace(),
+				Desc:              r.Description,
+				Private:           r.Private || !r.Public,
+				Fork:              r.Fork,
+				PushedAt:          r.PushedAt,
+			})
+		}
+		if len(repos) < perPage {
+			break
+		}
+		page++
+	}
+	return allRepos, nil
+}
+
+// listRepos fetches a single page of repositories of the user or the configured organization.
+// https://gitee.com/api/v5/swagger#/getV5UserRepos
+// https://gitee.com/api/v5/swagger#/getV5OrgsOrgRepos
+func (g *Gitee) listRepos(ctx context.Context, page, perPage int) ([]*Repo, error) {
+	queries := url.Values{}
+	queries.Set("type", "all")
+	queries.Set("per_page", fmt.Sprintf("%d", perPage))
+	queries.Set("page", fmt.Sprintf("%d", page))
+
+	var api string
+	if g.Org != "" {
+		api = fmt.Sprintf("%s/orgs/%s/repos?%s", g.BaseAPI, url.PathEscape(g.Org), queries.Encode())
+	} else {
+		api = fmt.Sprintf("%s/user/repos?%s", g.BaseAPI, queries.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.AccessToken)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("list repos failed, status: %s, body: %s", resp.Status, string(respBody)))
+	}
+
+	repos := make([]*Repo, 0)
+	if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return repos, nil
+}
+
+// SetTransport implements types.HTTPGit.
+func (g *Gitee) SetTransport(rt http.RoundTripper) {
+	g.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (g *Gitee) SetSSH(ssh *git.SSH) {
+	g.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (g *Gitee) SSHConfig() *git.SSH {
+	return g.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (g *Gitee) Credentials() (string, string) {
+	return g.Username, g.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (g *Gitee) SetCredentials(username, token string) {
+	g.Username, g.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (g *Gitee) CredentialURL() string {
+	return g.BaseURL
+}
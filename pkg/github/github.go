@@ -2,6 +2,7 @@ package github
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,25 +13,45 @@ import (
 	"strings"
 	"time"
 
+	"github.com/k8scat/mirror-git-go/pkg/git"
 	"github.com/k8scat/mirror-git-go/pkg/types"
 )
 
 var _ types.TargetGit = &GitHub{}
 var _ types.SourceGit = &GitHub{}
+var _ types.HTTPGit = &GitHub{}
+var _ types.SSHGit = &GitHub{}
+var _ types.CredentialGit = &GitHub{}
 
 type GitHub struct {
 	AccessToken string
 	Username    string
-	BaseAPI     string
-	IsOrg       bool
+	// BaseURL is the web URL of the instance, used for clone and push URLs
+	BaseURL string
+	// BaseAPI is the REST API URL
+	BaseAPI    string
+	GraphQLAPI string
+	// IsOrg marks Username as an organization, kept for compatibility with Namespace
+	IsOrg bool
+	// Namespace is the organization that target repositories are checked, created
+	// and pushed in. When empty, the user's personal account is used.
+	Namespace string
+
+	client *http.Client
+	ssh    *git.SSH
 }
 
+const (
+	// DefaultBaseURL is the URL of github.com
+	DefaultBaseURL = "https://github.com"
+	// DefaultBaseAPI is the REST API of github.com
+	DefaultBaseAPI = "https://api.github.com"
+)
+
 // ListRepos implements types.SourceGit.
-func (g *GitHub) ListRepos() ([]types.Repo, error) {
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
-	apiBaseURL := "https://api.github.com/user/repos"
+func (g *GitHub) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	client := g.httpClient()
+	apiBaseURL := g.BaseAPI + "/user/repos"
 	perPage := 100
 	page := 1
 
@@ -42,7 +63,7 @@ func (g *GitHub) ListRepos() ([]types.Repo, error) {
 		queryValues.Set("page", fmt.Sprintf("%d", page))
 		apiURL := apiBaseURL + "?" + queryValues.Encode()
 
-		req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
@@ -57,14 +78,18 @@ func (g *GitHub) ListRepos() ([]types.Repo, error) {
 
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
-			return nil, fmt.Errorf("GitHub API error: %v", resp.Status)
+			return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("GitHub API error: %v", resp.Status))
 		}
 
 		var rawRepos []struct {
-			Name        string `json:"name"`
-			FullName    string `json:"full_name"`
-			Description string `json:"description"`
-			Private     bool   `json:"private"`
+			Name        string    `json:"name"`
+			FullName    string    `json:"full_name"`
+			Description string    `json:"description"`
+			Private     bool      `json:"private"`
+			Fork        bool      `json:"fork"`
+			Archived    bool      `json:"archived"`
+			Size        int64     `json:"size"` // in KB
+			PushedAt    time.Time `json:"pushed_at"`
 		}
 		decoder := json.NewDecoder(resp.Body)
 		if err := decoder.Decode(&rawRepos); err != nil {
@@ -74,12 +99,16 @@ func (g *GitHub) ListRepos() ([]types.Repo, error) {
 		resp.Body.Close() // Close before next request
 
 		for _, r := range rawRepos {
-			repos = append(repos, types.NewRepo(
-				r.Name,
-				r.FullName,
-				r.Description,
-				r.Private,
-			))
+			repos = append(repos, &types.RepoImpl{
+				Path:              r.Name,
+				PathWithNamespace: r.FullName,
+				Desc:              r.Description,
+				Private:           r.Private,
+				Fork:              r.Fork,
+				Archived:          r.Archived,
+				Size:              r.Size * 1024,
+				PushedAt:          r.PushedAt,
+			})
 		}
 
 		if len(rawRepos) < perPage {
@@ -130,25 +159,67 @@ type CreateRepoMutationResponse struct {
 
 // NewGitHub creates a new GitHub client
 func NewGitHub(username, accessToken string, isOrg bool) *GitHub {
-	return &GitHub{
+	g := &GitHub{
 		Username:    username,
 		AccessToken: accessToken,
-		BaseAPI:     "https://api.github.com/graphql",
 		IsOrg:       isOrg,
 	}
+	g.SetBaseURL(DefaultBaseURL)
+	return g
 }
 
 // NewGitHubFromEnv creates a new GitHub client from environment variables
 func NewGitHubFromEnv() *GitHub {
-	return &GitHub{
-		Username:    os.Getenv("GITHUB_USERNAME"),
-		AccessToken: os.Getenv("GITHUB_ACCESS_TOKEN"),
-		BaseAPI:     "https://api.github.com/graphql",
-		IsOrg:       os.Getenv("GITHUB_IS_ORG") == "true",
+	g := NewGitHub(os.Getenv("GITHUB_USERNAME"), os.Getenv("GITHUB_ACCESS_TOKEN"), os.Getenv("GITHUB_IS_ORG") == "true")
+	if baseURL := os.Getenv("GITHUB_BASE_URL"); baseURL != "" {
+		g.SetBaseURL(baseURL)
+	}
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		g.SetAPIURL(apiURL)
+	}
+	if graphqlURL := os.Getenv("GITHUB_GRAPHQL_URL"); graphqlURL != "" {
+		g.GraphQLAPI = graphqlURL
+	}
+	g.Namespace = os.Getenv("GITHUB_NAMESPACE")
+	return g
+}
+
+// owner returns the account that owns target repositories
+func (g *GitHub) owner() string {
+	if g.Namespace != "" {
+		return g.Namespace
+	}
+	return g.Username
+}
+
+// isOrgOwner reports whether target repositories are owned by an organization
+func (g *GitHub) isOrgOwner() bool {
+	return g.IsOrg || (g.Namespace != "" && !strings.EqualFold(g.Namespace, g.Username))
+}
+
+// SetBaseURL points the client at github.com or a GitHub Enterprise Server instance,
+// deriving the REST and GraphQL API URLs from it.
+func (g *GitHub) SetBaseURL(baseURL string) {
+	g.BaseURL = strings.TrimSuffix(baseURL, "/")
+	if g.BaseURL == DefaultBaseURL {
+		g.SetAPIURL(DefaultBaseAPI)
+		return
+	}
+	g.SetAPIURL(g.BaseURL + "/api/v3")
+}
+
+// SetAPIURL sets the REST API URL and derives the GraphQL API URL from it:
+// https://api.github.com/graphql on github.com, https://HOST/api/graphql on GitHub Enterprise Server.
+func (g *GitHub) SetAPIURL(apiURL string) {
+	g.BaseAPI = strings.TrimSuffix(apiURL, "/")
+	if strings.HasSuffix(g.BaseAPI, "/api/v3") {
+		g.GraphQLAPI = strings.TrimSuffix(g.BaseAPI, "/v3") + "/graphql"
+		return
 	}
+	g.GraphQLAPI = g.BaseAPI + "/graphql"
 }
 
-func (g *GitHub) graphql(query string, variables map[string]any, response any) error {
+func (g *GitHub) graphql(ctx context.Context, query string, variables map[string]any, response any) error {
 	request := GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -159,7 +230,7 @@ func (g *GitHub) graphql(query string, variables map[string]any, response any) e
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, g.BaseAPI, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.GraphQLAPI, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -167,7 +238,7 @@ func (g *GitHub) graphql(query string, variables map[string]any, response any) e
 	req.Header.Set("Authorization", "Bearer "+g.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
@@ -176,7 +247,7 @@ func (g *GitHub) graphql(query string, variables map[string]any, response any) e
 
 	fmt.Println(resp.StatusCode)
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("request failed with status %d", resp.StatusCode))
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
@@ -186,7 +257,7 @@ func (g *GitHub) graphql(query string, variables map[string]any, response any) e
 	return nil
 }
 
-func (g *GitHub) IsRepoExist(name string) (bool, error) {
+func (g *GitHub) IsRepoExist(ctx context.Context, name string) (bool, error) {
 	query := `
 		query ($repo_owner: String!, $repo_name: String!) {
 			repository(owner: $repo_owner, name: $repo_name) {
@@ -196,12 +267,12 @@ func (g *GitHub) IsRepoExist(name string) (bool, error) {
 	`
 
 	variables := map[string]any{
-		"repo_owner": g.Username,
+		"repo_owner": g.owner(),
 		"repo_name":  name,
 	}
 
 	var response RepositoryQueryResponse
-	err := g.graphql(query, variables, &response)
+	err := g.graphql(ctx, query, variables, &response)
 	if err != nil {
 		return false, fmt.Errorf("failed to execute GraphQL query: %w", err)
 	}
@@ -218,9 +289,9 @@ func (g *GitHub) IsRepoExist(name string) (bool, error) {
 	return response.Data.Repository != nil && response.Data.Repository.ID != "", nil
 }
 
-func (g *GitHub) CreateRepo(name string, desc string, private bool) error {
+func (g *GitHub) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
 	// Check if repository already exists
-	exists, err := g.IsRepoExist(name)
+	exists, err := g.IsRepoExist(ctx, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if repository exists: %w", err)
 	}
@@ -229,14 +300,14 @@ func (g *GitHub) CreateRepo(name string, desc string, private bool) error {
 		return nil
 	}
 
-	if g.IsOrg {
-		return g.createOrgRepo(name, desc, private)
+	if g.isOrgOwner() {
+		return g.createOrgRepo(ctx, name, desc, private)
 	}
-	return g.createUserRepo(name, desc, private)
+	return g.createUserRepo(ctx, name, desc, private)
 }
 
-func (g *GitHub) createOrgRepo(name string, desc string, private bool) error {
-	apiURL := fmt.Sprintf("https://api.github.com/orgs/%s/repos", g.Username)
+func (g *GitHub) createOrgRepo(ctx context.Context, name string, desc string, private bool) error {
+	apiURL := fmt.Sprintf("%s/orgs/%s/repos", g.BaseAPI, g.owner())
 
 	payload := map[string]any{
 		"name":        name,
@@ -248,7 +319,7 @@ func (g *GitHub) createOrgRepo(name string, desc string, private bool) error {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -257,7 +328,7 @@ func (g *GitHub) createOrgRepo(name string, desc string, private bool) error {
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
@@ -266,13 +337,13 @@ func (g *GitHub) createOrgRepo(name string, desc string, private bool) error {
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create org repo: %s", string(body))
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("failed to create org repo: %s", string(body)))
 	}
 	return nil
 }
 
-func (g *GitHub) createUserRepo(name string, desc string, private bool) error {
-	apiURL := "https://api.github.com/user/repos"
+func (g *GitHub) createUserRepo(ctx context.Context, name string, desc string, private bool) error {
+	apiURL := g.BaseAPI + "/user/repos"
 
 	payload := map[string]any{
 		"name":        name,
@@ -285,7 +356,7 @@ func (g *GitHub) createUserRepo(name string, desc string, private bool) error {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payloadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(payloadBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -294,7 +365,7 @@ func (g *GitHub) createUserRepo(name string, desc string, private bool) error {
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
@@ -303,15 +374,53 @@ func (g *GitHub) createUserRepo(name string, desc string, private bool) error {
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create user repo: %s", string(body))
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("failed to create user repo: %s", string(body)))
 	}
 	return nil
 }
 
-func (g *GitHub) GetTargetRepoAddr(path string) string {
-	return fmt.Sprintf("https://%s:%s@github.com/%s/%s.git", g.Username, g.AccessToken, g.Username, path)
+func (g *GitHub) GetTargetRepoAddr(ctx context.Context, path string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, g.owner()+"/"+path)
 }
 
 func (g *GitHub) GetSourceRepoAddr(pathWithNamespace string) string {
-	return fmt.Sprintf("https://%s:%s@github.com/%s.git", g.Username, g.AccessToken, pathWithNamespace)
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, pathWithNamespace)
+}
+
+// SetTransport implements types.HTTPGit.
+func (g *GitHub) SetTransport(rt http.RoundTripper) {
+	g.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (g *GitHub) SetSSH(ssh *git.SSH) {
+	g.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (g *GitHub) SSHConfig() *git.SSH {
+	return g.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (g *GitHub) Credentials() (string, string) {
+	return g.Username, g.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (g *GitHub) SetCredentials(username, token string) {
+	g.Username, g.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (g *GitHub) CredentialURL() string {
+	return g.BaseURL
+}
+
+// httpClient returns the client of the API requests
+func (g *GitHub) httpClient() *http.Client {
+	if g.client == nil {
+		return &http.Client{Timeout: 60 * time.Second}
+	}
+	return g.client
 }
@@ -2,6 +2,7 @@ package gitlab
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,39 +11,82 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/k8scat/mirror-git-go/pkg/git"
 	"github.com/k8scat/mirror-git-go/pkg/types"
 )
 
 var _ types.TargetGit = &GitLab{}
+var _ types.SourceGit = &GitLab{}
+var _ types.NestedTargetGit = &GitLab{}
+var _ types.HTTPGit = &GitLab{}
+var _ types.SSHGit = &GitLab{}
+var _ types.CredentialGit = &GitLab{}
+var _ types.PrePushGit = &GitLab{}
+var _ types.NormalizingTargetGit = &GitLab{}
 
 type GitLab struct {
 	AccessToken string
 	Username    string
-	BaseAPI     string
+	// BaseURL is the web URL of the instance, used for clone and push URLs
+	BaseURL string
+	BaseAPI string
+	// Group limits ListRepos to the projects of a group (ID or full path),
+	// including its subgroups. When empty, all projects the user is a member of are listed.
+	Group string
+	// Namespace is the group (ID or full path, subgroups allowed) that target projects
+	// are checked, created and pushed in. When empty, the user's namespace is used.
+	Namespace string
+
+	client *http.Client
+	ssh    *git.SSH
+
+	mu                sync.Mutex
+	resolvedNamespace *NamespaceInfo
+	// groupLocks serialize the lookup and creation of each group by full path
+	groupLocks map[string]*sync.Mutex
 }
 
 func (g *GitLab) Name() string {
 	return "gitlab"
 }
 
+// DefaultBaseURL is the URL of GitLab.com
+const DefaultBaseURL = "https://gitlab.com"
+
 // NewGitLab creates a new GitLab client
 func NewGitLab(username, accessToken string) *GitLab {
-	return &GitLab{
+	g := &GitLab{
 		Username:    username,
 		AccessToken: accessToken,
-		BaseAPI:     "https://gitlab.com/api/v4",
 	}
+	g.SetBaseURL(DefaultBaseURL)
+	return g
 }
 
 // NewGitLabFromEnv creates a new GitLab client from environment variables
 func NewGitLabFromEnv() *GitLab {
-	return &GitLab{
-		Username:    os.Getenv("GITLAB_USERNAME"),
-		AccessToken: os.Getenv("GITLAB_ACCESS_TOKEN"),
-		BaseAPI:     "https://gitlab.com/api/v4",
+	g := NewGitLab(os.Getenv("GITLAB_USERNAME"), os.Getenv("GITLAB_ACCESS_TOKEN"))
+	g.Group = os.Getenv("GITLAB_GROUP")
+	g.Namespace = os.Getenv("GITLAB_NAMESPACE")
+	if baseURL := os.Getenv("GITLAB_BASE_URL"); baseURL != "" {
+		g.SetBaseURL(baseURL)
+	}
+	if apiURL := os.Getenv("GITLAB_API_URL"); apiURL != "" {
+		g.BaseAPI = strings.TrimSuffix(apiURL, "/")
 	}
+	return g
+}
+
+// SetBaseURL points the client at a GitLab instance, deriving the REST API URL from it
+func (g *GitLab) SetBaseURL(baseURL string) {
+	g.BaseURL = strings.TrimSuffix(baseURL, "/")
+	g.BaseAPI = g.BaseURL + "/api/v4"
 }
 
 // CreateRepoRequest represents the request payload for creating a repository
@@ -50,6 +94,41 @@ type CreateRepoRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Visibility  string `json:"visibility"`
+	NamespaceID int    `json:"namespace_id,omitempty"`
+}
+
+// CreateGroupRequest represents the request payload for creating a subgroup
+type CreateGroupRequest struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	ParentID   int    `json:"parent_id"`
+	Visibility string `json:"visibility"`
+}
+
+// NamespaceInfo represents a namespace returned by the GitLab namespaces API
+type NamespaceInfo struct {
+	ID       int    `json:"id"`
+	Kind     string `json:"kind"`
+	FullPath string `json:"full_path"`
+}
+
+// Project represents a project returned by the GitLab projects API
+type Project struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	Visibility        string `json:"visibility"`
+	Archived          bool   `json:"archived"`
+	ForkedFromProject *struct {
+		ID int `json:"id"`
+	} `json:"forked_from_project"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// Statistics is only returned to members with at least the Reporter role
+	Statistics *struct {
+		RepositorySize int64 `json:"repository_size"`
+	} `json:"statistics"`
 }
 
 // ProtectedBranch represents a protected branch in GitLab
@@ -69,21 +148,202 @@ type AccessLevel struct {
 	Description string `json:"access_level_description"`
 }
 
-func (g *GitLab) IsRepoExist(repoName string) (bool, error) {
-	repoName = processRepoName(repoName)
+// GetNamespace returns the target namespace, looking it up once when Namespace is set
+// https://docs.gitlab.com/ee/api/namespaces.html#get-namespace-by-id
+// Concurrent first calls may each look it up, the lock is not held during the request.
+func (g *GitLab) GetNamespace(ctx context.Context) (*NamespaceInfo, error) {
+	g.mu.Lock()
+	resolved := g.resolvedNamespace
+	g.mu.Unlock()
+
+	if resolved != nil {
+		return resolved, nil
+	}
+	if g.Namespace == "" {
+		return &NamespaceInfo{Kind: "user", FullPath: g.Username}, nil
+	}
+
+	apiURL := fmt.Sprintf("%s/namespaces/%s", g.BaseAPI, url.QueryEscape(g.Namespace))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Private-Token", g.AccessToken)
+
+	client := g.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("get namespace %s failed, status code: %d, body: %s", g.Namespace, resp.StatusCode, string(body)))
+	}
+
+	ns := &NamespaceInfo{}
+	if err := json.Unmarshal(body, ns); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	g.mu.Lock()
+	g.resolvedNamespace = ns
+	g.mu.Unlock()
+	return ns, nil
+}
+
+// targetPath returns the full path of a target project
+func (g *GitLab) targetPath(ctx context.Context, repoName string) (string, error) {
+	ns, err := g.GetNamespace(ctx)
+	if err != nil {
+		return "", err
+	}
+	return ns.FullPath + "/" + processRepoPath(repoName), nil
+}
+
+// SupportsNestedPaths implements types.NestedTargetGit.
+// Subgroups can only be created below a group, not in a user namespace.
+func (g *GitLab) SupportsNestedPaths() bool {
+	return g.Namespace != ""
+}
+
+// EnsureGroup returns the ID of the group with the given full path,
+// creating it and any missing parent groups below the target namespace.
+// Concurrent calls for the same group wait for each other, so that only one creates it.
+// https://docs.gitlab.com/ee/api/groups.html#new-subgroup
+func (g *GitLab) EnsureGroup(ctx context.Context, fullPath string) (int, error) {
+	lock := g.groupLock(fullPath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	apiURL := fmt.Sprintf("%s/groups/%s", g.BaseAPI, url.QueryEscape(fullPath))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Private-Token", g.AccessToken)
+
+	client := g.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var group NamespaceInfo
+		if err := json.Unmarshal(body, &group); err != nil {
+			return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return group.ID, nil
+	case http.StatusNotFound:
+	default:
+		return 0, types.NewStatusError(resp.StatusCode, fmt.Errorf("get group %s failed, status code: %d, body: %s", fullPath, resp.StatusCode, string(body)))
+	}
+
+	ns, err := g.GetNamespace(ctx)
+	if err != nil {
+		return 0, err
+	}
+	parentPath, name := path.Split(fullPath)
+	parentPath = strings.TrimSuffix(parentPath, "/")
+	if ns.Kind != "group" || !strings.HasPrefix(parentPath+"/", ns.FullPath+"/") {
+		return 0, fmt.Errorf("group %s does not exist and cannot be created outside of the target group %s", fullPath, ns.FullPath)
+	}
+
+	parentID, err := g.EnsureGroup(ctx, parentPath)
+	if err != nil {
+		return 0, err
+	}
+
+	slog.Info("group not exists, create it", "group", fullPath)
+	data, err := json.Marshal(CreateGroupRequest{
+		Name:       name,
+		Path:       name,
+		ParentID:   parentID,
+		Visibility: "private",
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request data: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, g.BaseAPI+"/groups", bytes.NewBuffer(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Private-Token", g.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return 0, types.NewStatusError(resp.StatusCode, fmt.Errorf("create group %s failed, status code: %d, body: %s", fullPath, resp.StatusCode, string(body)))
+	}
+
+	var group NamespaceInfo
+	if err := json.Unmarshal(body, &group); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return group.ID, nil
+}
+
+// groupLock returns the lock of the group with the given full path. Parent groups are
+// locked while their children are, never the other way round.
+func (g *GitLab) groupLock(fullPath string) *sync.Mutex {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.groupLocks == nil {
+		g.groupLocks = make(map[string]*sync.Mutex)
+	}
+	lock, ok := g.groupLocks[fullPath]
+	if !ok {
+		lock = &sync.Mutex{}
+		g.groupLocks[fullPath] = lock
+	}
+	return lock
+}
+
+// IsRepoExist implements types.TargetGit.
+func (g *GitLab) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
+	projectPath, err := g.targetPath(ctx, repoName)
+	if err != nil {
+		return false, err
+	}
 	// Get single project: GET /projects/:id
 	// Use URL encoding for the project path
-	path := url.QueryEscape(fmt.Sprintf("%s/%s", g.Username, repoName))
-	apiURL := fmt.Sprintf("%s/projects/%s", g.BaseAPI, path)
+	apiURL := fmt.Sprintf("%s/projects/%s", g.BaseAPI, url.QueryEscape(projectPath))
 
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Private-Token", g.AccessToken)
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return false, fmt.Errorf("failed to execute request: %w", err)
@@ -91,18 +351,6 @@ func (g *GitLab) IsRepoExist(repoName string) (bool, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusOK {
-		branches, err := g.ListProtectedBranches(path)
-		if err != nil {
-			slog.Error("list protected branches failed", "error", err, "repo", repoName)
-		} else {
-			for _, branch := range branches {
-				slog.Info("unprotected branch", "repo", repoName, "branch", branch.Name)
-				if err := g.UnprotectBranch(path, branch.Name); err != nil {
-					slog.Error("unprotect branch failed", "error", err, "repo", repoName, "branch", branch.Name)
-				}
-			}
-		}
-
 		return true, nil
 	}
 	if resp.StatusCode == http.StatusNotFound {
@@ -113,7 +361,27 @@ func (g *GitLab) IsRepoExist(repoName string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("failed to read response body: %w", err)
 	}
-	return false, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b))
+	return false, types.NewStatusError(resp.StatusCode, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(b)))
+}
+
+// PreparePush implements types.PrePushGit.
+// Protected branches reject the forced updates of a mirror push, so they are unprotected.
+func (g *GitLab) PreparePush(ctx context.Context, repoName string) error {
+	projectPath, err := g.targetPath(ctx, repoName)
+	if err != nil {
+		return err
+	}
+	branches, err := g.ListProtectedBranches(ctx, projectPath)
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		slog.Info("unprotected branch", "repo", repoName, "branch", branch.Name)
+		if err := g.UnprotectBranch(ctx, projectPath, branch.Name); err != nil {
+			slog.Error("unprotect branch failed", "error", err, "repo", repoName, "branch", branch.Name)
+		}
+	}
+	return nil
 }
 
 func processRepoName(name string) string {
@@ -130,19 +398,50 @@ func processRepoName(name string) string {
 	return strings.ToLower(strings.Join(newParts, "-"))
 }
 
-func (g *GitLab) CreateRepo(name, desc string, isPrivate bool) error {
+// NormalizePath implements types.NormalizingTargetGit.
+// Project paths are lowercase with runs of dashes and underscores folded into one dash.
+func (g *GitLab) NormalizePath(p string) string {
+	return processRepoPath(p)
+}
+
+// processRepoPath applies processRepoName to every segment of a nested path
+func processRepoPath(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = processRepoName(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func (g *GitLab) CreateRepo(ctx context.Context, name, desc string, isPrivate bool) error {
 	visibility := "public"
 	if isPrivate {
 		visibility = "private"
 	}
 
-	repoName := processRepoName(name)
+	dir, repoName := path.Split(processRepoPath(name))
 
 	data := CreateRepoRequest{
 		Name:        repoName,
 		Description: desc,
 		Visibility:  visibility,
 	}
+	if g.Namespace != "" {
+		ns, err := g.GetNamespace(ctx)
+		if err != nil {
+			return err
+		}
+		data.NamespaceID = ns.ID
+		if dir != "" {
+			groupID, err := g.EnsureGroup(ctx, ns.FullPath+"/"+strings.TrimSuffix(dir, "/"))
+			if err != nil {
+				return fmt.Errorf("failed to create parent group: %w", err)
+			}
+			data.NamespaceID = groupID
+		}
+	} else if dir != "" {
+		return fmt.Errorf("nested repository path %s requires a target group namespace", name)
+	}
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
@@ -150,7 +449,7 @@ func (g *GitLab) CreateRepo(name, desc string, isPrivate bool) error {
 	}
 
 	apiURL := fmt.Sprintf("%s/projects", g.BaseAPI)
-	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -158,7 +457,7 @@ func (g *GitLab) CreateRepo(name, desc string, isPrivate bool) error {
 	req.Header.Set("Private-Token", g.AccessToken)
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
@@ -166,33 +465,135 @@ func (g *GitLab) CreateRepo(name, desc string, isPrivate bool) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create repository, status code: %d", resp.StatusCode)
+		return types.NewStatusError(resp.StatusCode, fmt.Errorf("failed to create repository, status code: %d", resp.StatusCode))
 	}
 
 	return nil
 }
 
 // GetTargetRepoAddr implements types.TargetGit.
-func (g *GitLab) GetTargetRepoAddr(path string) string {
-	path = processRepoName(path)
-	return fmt.Sprintf("https://%s:%s@gitlab.com/%s/%s.git", g.Username, g.AccessToken, g.Username, path)
+func (g *GitLab) GetTargetRepoAddr(ctx context.Context, path string) string {
+	// The namespace is usually cached by IsRepoExist before the push address is needed
+	projectPath, err := g.targetPath(ctx, path)
+	if err != nil {
+		slog.Error("resolve target namespace failed", "error", err, "namespace", g.Namespace)
+		projectPath = g.Namespace + "/" + processRepoPath(path)
+	}
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, projectPath)
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+func (g *GitLab) GetSourceRepoAddr(pathWithNamespace string) string {
+	return git.CloneURL(g.ssh, g.BaseURL, g.Username, g.AccessToken, pathWithNamespace)
+}
+
+// ListRepos implements types.SourceGit.
+// https://docs.gitlab.com/ee/api/projects.html#list-all-projects
+// https://docs.gitlab.com/ee/api/groups.html#list-a-groups-projects
+func (g *GitLab) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	allRepos := make([]types.Repo, 0)
+	page := 1
+	perPage := 100
+	for {
+		projects, nextPage, err := g.listProjects(ctx, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			repo := &types.RepoImpl{
+				Path:              p.Path,
+				PathWithNamespace: p.PathWithNamespace,
+				Desc:              p.Description,
+				Private:           p.Visibility != "public",
+				Fork:              p.ForkedFromProject != nil,
+				Archived:          p.Archived,
+				PushedAt:          p.LastActivityAt,
+			}
+			if p.Statistics != nil {
+				repo.Size = p.Statistics.RepositorySize
+			}
+			allRepos = append(allRepos, repo)
+		}
+		if nextPage == 0 {
+			break
+		}
+		page = nextPage
+	}
+	return allRepos, nil
+}
+
+// listProjects fetches a single page of projects and returns the next page number,
+// which is 0 when there are no more pages.
+func (g *GitLab) listProjects(ctx context.Context, page, perPage int) ([]Project, int, error) {
+	queryValues := url.Values{}
+	queryValues.Set("per_page", fmt.Sprintf("%d", perPage))
+	queryValues.Set("page", fmt.Sprintf("%d", page))
+	queryValues.Set("order_by", "id")
+	queryValues.Set("sort", "asc")
+	queryValues.Set("statistics", "true")
+
+	var apiURL string
+	if g.Group != "" {
+		queryValues.Set("include_subgroups", "true")
+		apiURL = fmt.Sprintf("%s/groups/%s/projects?%s", g.BaseAPI, url.QueryEscape(g.Group), queryValues.Encode())
+	} else {
+		queryValues.Set("membership", "true")
+		apiURL = fmt.Sprintf("%s/projects?%s", g.BaseAPI, queryValues.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Private-Token", g.AccessToken)
+
+	client := g.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, types.NewStatusError(resp.StatusCode, fmt.Errorf("list projects failed, status code: %d, body: %s", resp.StatusCode, string(body)))
+	}
+
+	var projects []Project
+	if err := json.Unmarshal(body, &projects); err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	// GitLab omits X-Next-Page for very large result sets, so fall back to the page size.
+	nextPage := 0
+	if next := resp.Header.Get("X-Next-Page"); next != "" {
+		nextPage, _ = strconv.Atoi(next)
+	} else if resp.Header.Get("X-Page") == "" && len(projects) == perPage {
+		nextPage = page + 1
+	}
+	return projects, nextPage, nil
 }
 
 // ListProtectedBranches lists all protected branches for a project
 // https://docs.gitlab.com/ee/api/protected_branches.html#list-protected-branches
-func (g *GitLab) ListProtectedBranches(projectID string) ([]ProtectedBranch, error) {
+func (g *GitLab) ListProtectedBranches(ctx context.Context, projectID string) ([]ProtectedBranch, error) {
 	// Use URL encoding for the project ID
 	encodedProjectID := url.QueryEscape(projectID)
 	apiURL := fmt.Sprintf("%s/projects/%s/protected_branches", g.BaseAPI, encodedProjectID)
 
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Private-Token", g.AccessToken)
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
@@ -206,7 +607,7 @@ func (g *GitLab) ListProtectedBranches(projectID string) ([]ProtectedBranch, err
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("list protected branches failed: %s", string(body))
-		return nil, fmt.Errorf("list protected branches failed, status code: %d, body: %s", resp.StatusCode, string(body))
+		return nil, types.NewStatusError(resp.StatusCode, fmt.Errorf("list protected branches failed, status code: %d, body: %s", resp.StatusCode, string(body)))
 	}
 
 	var branches []ProtectedBranch
@@ -219,20 +620,20 @@ func (g *GitLab) ListProtectedBranches(projectID string) ([]ProtectedBranch, err
 
 // UnprotectBranch unprotects the given protected branch or wildcard protected branch
 // https://docs.gitlab.com/ee/api/protected_branches.html#unprotect-repository-branches
-func (g *GitLab) UnprotectBranch(projectID, branchName string) error {
+func (g *GitLab) UnprotectBranch(ctx context.Context, projectID, branchName string) error {
 	// Use URL encoding for both project ID and branch name
 	encodedProjectID := url.QueryEscape(projectID)
 	encodedBranchName := url.QueryEscape(branchName)
 	apiURL := fmt.Sprintf("%s/projects/%s/protected_branches/%s", g.BaseAPI, encodedProjectID, encodedBranchName)
 
-	req, err := http.NewRequest(http.MethodDelete, apiURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, apiURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Private-Token", g.AccessToken)
 
-	client := &http.Client{}
+	client := g.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to execute request: %w", err)
@@ -248,5 +649,43 @@ func (g *GitLab) UnprotectBranch(projectID, branchName string) error {
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return fmt.Errorf("unprotect repository branch failed, status code: %d, body: %s", resp.StatusCode, string(body))
+	return types.NewStatusError(resp.StatusCode, fmt.Errorf("unprotect repository branch failed, status code: %d, body: %s", resp.StatusCode, string(body)))
+}
+
+// SetTransport implements types.HTTPGit.
+func (g *GitLab) SetTransport(rt http.RoundTripper) {
+	g.client = &http.Client{Transport: rt}
+}
+
+// SetSSH implements types.SSHGit.
+func (g *GitLab) SetSSH(ssh *git.SSH) {
+	g.ssh = ssh
+}
+
+// SSHConfig implements types.SSHGit.
+func (g *GitLab) SSHConfig() *git.SSH {
+	return g.ssh
+}
+
+// Credentials implements types.CredentialGit.
+func (g *GitLab) Credentials() (string, string) {
+	return g.Username, g.AccessToken
+}
+
+// SetCredentials implements types.CredentialGit.
+func (g *GitLab) SetCredentials(username, token string) {
+	g.Username, g.AccessToken = username, token
+}
+
+// CredentialURL implements types.CredentialGit.
+func (g *GitLab) CredentialURL() string {
+	return g.BaseURL
+}
+
+// httpClient returns the client of the API requests
+func (g *GitLab) httpClient() *http.Client {
+	if g.client == nil {
+		return &http.Client{Timeout: 60 * time.Second}
+	}
+	return g.client
 }
@@ -1,13 +1,19 @@
 package gitlab
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestIsRepoExist(t *testing.T) {
 	g := NewGitLabFromEnv()
-	exists, err := g.IsRepoExist("test")
+	exists, err := g.IsRepoExist(context.Background(), "test")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -20,7 +26,7 @@ func TestIsRepoExist(t *testing.T) {
 
 func TestCreateRepo(t *testing.T) {
 	g := NewGitLabFromEnv()
-	err := g.CreateRepo("test", "This is a test repository", true)
+	err := g.CreateRepo(context.Background(), "test", "This is a test repository", true)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -31,7 +37,7 @@ func TestListProtectedBranches(t *testing.T) {
 	g := NewGitLabFromEnv()
 	// Use your actual project ID or namespace/project-name format
 	projectID := "user/repo" // Replace with actual project
-	branches, err := g.ListProtectedBranches(projectID)
+	branches, err := g.ListProtectedBranches(context.Background(), projectID)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -47,9 +53,289 @@ func TestUnprotectBranch(t *testing.T) {
 	projectID := "user/repo" // Replace with actual project
 	branchName := "main"     // Replace with actual branch name
 
-	err := g.UnprotectBranch(projectID, branchName)
+	err := g.UnprotectBranch(context.Background(), projectID, branchName)
 	if err != nil {
 		t.Fatal(err)
 	}
 	fmt.Printf("Successfully unprotected branch: %s\n", branchName)
 }
+
+func TestListRepos(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.Header.Get("Private-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.EscapedPath() != "/groups/parent%2Fsub/projects" || r.URL.Query().Get("include_subgroups") != "true" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			w.Header().Set("X-Page", "1")
+			w.Header().Set("X-Next-Page", "2")
+			fmt.Fprint(w, `[{"path":"api","path_with_namespace":"parent/sub/api","description":"API","visibility":"private"}]`)
+		case "2":
+			w.Header().Set("X-Page", "2")
+			w.Header().Set("X-Next-Page", "")
+			fmt.Fprint(w, `[{"path":"web","path_with_namespace":"parent/sub/deep/web","visibility":"public"}]`)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGitLab("user", "token")
+	g.BaseAPI = srv.URL
+	g.Group = "parent/sub"
+	repos, err := g.ListRepos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 2 {
+		t.Fatalf("expected 2 repos, got %d (requests: %v)", len(repos), requests)
+	}
+	if repos[0].GetPathWithNamespace() != "parent/sub/api" || !repos[0].GetPrivate() || repos[0].GetDesc() != "API" {
+		t.Errorf("unexpected first repo: %+v", repos[0])
+	}
+	if repos[1].GetPath() != "web" || repos[1].GetPrivate() {
+		t.Errorf("unexpected second repo: %+v", repos[1])
+	}
+}
+
+func TestTargetNamespace(t *testing.T) {
+	var created CreateRepoRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/namespaces/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "parent/sub" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id":7,"kind":"group","full_path":"parent/sub"}`)
+	})
+	mux.HandleFunc("GET /api/v4/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "parent/sub/my-api" {
+			t.Errorf("unexpected project lookup: %s", r.PathValue("id"))
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("POST /api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g := NewGitLab("bot", "token")
+	g.SetBaseURL(srv.URL)
+	g.Namespace = "parent/sub"
+
+	exists, err := g.IsRepoExist(context.Background(), "My_API")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatal("expected project to not exist")
+	}
+	if err := g.CreateRepo(context.Background(), "My_API", "", true); err != nil {
+		t.Fatal(err)
+	}
+	if created.NamespaceID != 7 || created.Name != "my-api" {
+		t.Errorf("unexpected create request: %+v", created)
+	}
+	if got, want := g.GetTargetRepoAddr(context.Background(), "My_API"), "http://bot:token@"+srv.Listener.Addr().String()+"/parent/sub/my-api.git"; got != want {
+		t.Errorf("GetTargetRepoAddr() = %q, want %q", got, want)
+	}
+}
+
+func TestCreateNestedRepo(t *testing.T) {
+	groups := map[string]int{"parent": 1}
+	var created CreateRepoRequest
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/namespaces/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"kind":"group","full_path":"parent"}`)
+	})
+	mux.HandleFunc("GET /api/v4/groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, ok := groups[r.PathValue("id")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d}`, id)
+	})
+	mux.HandleFunc("POST /api/v4/groups", func(w http.ResponseWriter, r *http.Request) {
+		var req CreateGroupRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		for fullPath, id := range groups {
+			if id == req.ParentID {
+				groups[fullPath+"/"+req.Path] = len(groups) + 1
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"id":%d}`, len(groups))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	mux.HandleFunc("POST /api/v4/projects", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g := NewGitLab("bot", "token")
+	g.SetBaseURL(srv.URL)
+	if g.SupportsNestedPaths() {
+		t.Error("user namespaces cannot hold nested paths")
+	}
+	g.Namespace = "parent"
+	if !g.SupportsNestedPaths() {
+		t.Error("group namespaces should support nested paths")
+	}
+
+	if err := g.CreateRepo(context.Background(), "GroupA/Sub_Team/api", "", true); err != nil {
+		t.Fatal(err)
+	}
+	subID, ok := groups["parent/groupa/sub-team"]
+	if !ok {
+		t.Fatalf("subgroups not created: %v", groups)
+	}
+	if created.NamespaceID != subID || created.Name != "api" {
+		t.Errorf("unexpected create request: %+v", created)
+	}
+	if got := g.NormalizePath("GroupA/Sub__Team/my_api"); got != "groupa/sub-team/my-api" {
+		t.Errorf("NormalizePath() = %q, want the path the project is created at", got)
+	}
+}
+
+func TestPreparePush(t *testing.T) {
+	var unprotected []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1}`)
+	})
+	mux.HandleFunc("GET /api/v4/projects/{id}/protected_branches", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"name":"main"},{"name":"release/*"}]`)
+	})
+	mux.HandleFunc("DELETE /api/v4/projects/{id}/protected_branches/{branch}", func(w http.ResponseWriter, r *http.Request) {
+		unprotected = append(unprotected, r.PathValue("id")+":"+r.PathValue("branch"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g := NewGitLab("bot", "token")
+	g.SetBaseURL(srv.URL)
+
+	// Checking for the project does not touch its branches
+	if exists, err := g.IsRepoExist(context.Background(), "api"); err != nil || !exists {
+		t.Fatalf("IsRepoExist() = %v, %v", exists, err)
+	}
+	if len(unprotected) != 0 {
+		t.Fatalf("IsRepoExist() unprotected %v", unprotected)
+	}
+	if err := g.PreparePush(context.Background(), "api"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"bot/api:main", "bot/api:release/*"}; fmt.Sprint(unprotected) != fmt.Sprint(want) {
+		t.Errorf("unprotected %v, want %v", unprotected, want)
+	}
+}
+
+func TestEnsureGroupConcurrent(t *testing.T) {
+	var mu sync.Mutex
+	groups := map[string]int{"parent": 1}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/namespaces/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"kind":"group","full_path":"parent"}`)
+	})
+	mux.HandleFunc("GET /api/v4/groups/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		id, ok := groups[r.PathValue("id")]
+		mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"id":%d}`, id)
+	})
+	mux.HandleFunc("POST /api/v4/groups", func(w http.ResponseWriter, r *http.Request) {
+		var req CreateGroupRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := groups["parent/"+req.Path]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":{"path":["has already been taken"]}}`)
+			return
+		}
+		groups["parent/"+req.Path] = len(groups) + 1
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":%d}`, len(groups))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g := NewGitLab("bot", "token")
+	g.SetBaseURL(srv.URL)
+	g.Namespace = "parent"
+
+	var wg sync.WaitGroup
+	ids := make([]int, 5)
+	errs := make([]error, len(ids))
+	for i := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids[i], errs[i] = g.EnsureGroup(context.Background(), "parent/team")
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("EnsureGroup() = %v", err)
+		} else if ids[i] != 2 {
+			t.Errorf("EnsureGroup() = %d, want 2", ids[i])
+		}
+	}
+}
+
+func TestGetNamespaceConcurrent(t *testing.T) {
+	// Every lookup waits until all of them are in flight, which only happens
+	// if workers do not wait for each other's lookups
+	const workers = 5
+	var mu sync.Mutex
+	inFlight := 0
+	all := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if inFlight++; inFlight == workers {
+			close(all)
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+		case <-time.After(10 * time.Second):
+			http.Error(w, "lookups are serialized", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"id":7,"kind":"group","full_path":"parent/sub"}`)
+	}))
+	defer srv.Close()
+
+	g := NewGitLab("bot", "token")
+	g.BaseAPI = srv.URL
+	g.Namespace = "parent/sub"
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ns, err := g.GetNamespace(context.Background()); err != nil || ns.ID != 7 {
+				t.Errorf("GetNamespace() = %+v, %v", ns, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
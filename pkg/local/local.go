@@ -1,23 +1,158 @@
 package local
 
-import "github.com/k8scat/mirror-git-go/pkg/types"
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
 
 var _ types.TargetGit = &Local{}
+var _ types.SourceGit = &Local{}
+var _ types.NestedTargetGit = &Local{}
+
+// defaultDescription is the placeholder git writes into the description file of new repositories
+const defaultDescription = "Unnamed repository; edit this file 'description' to name the repository."
+
+type Local struct {
+	// Dir is the directory scanned for repositories when used as a source
+	Dir string
+
+	mu   sync.Mutex
+	dirs map[string]string
+}
+
+// NewLocal creates a local source that scans dir for git repositories
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
 
-type Local struct{}
+// NewLocalFromEnv creates a local source from environment variables
+func NewLocalFromEnv() *Local {
+	return NewLocal(os.Getenv("LOCAL_SOURCE_DIR"))
+}
 
-func (l *Local) CreateRepo(name string, desc string, private bool) error {
+func (l *Local) CreateRepo(ctx context.Context, name string, desc string, private bool) error {
 	return nil
 }
 
-func (l *Local) GetTargetRepoAddr(path string) string {
+func (l *Local) GetTargetRepoAddr(ctx context.Context, path string) string {
 	return ""
 }
 
-func (l *Local) IsRepoExist(repoName string) (bool, error) {
+func (l *Local) IsRepoExist(ctx context.Context, repoName string) (bool, error) {
 	return true, nil
 }
 
+// SupportsNestedPaths implements types.NestedTargetGit.
+func (l *Local) SupportsNestedPaths() bool {
+	return true
+}
+
 func (l *Local) Name() string {
 	return "local"
 }
+
+// ListRepos implements types.SourceGit.
+// It walks Dir and returns every bare and non-bare repository found,
+// namespaced by its path relative to Dir. Nested repositories are not scanned.
+func (l *Local) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	if l.Dir == "" {
+		return nil, fmt.Errorf("source directory is not set")
+	}
+	root, err := filepath.Abs(l.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve source directory failed: %w", err)
+	}
+
+	dirs := make(map[string]string)
+	repos := make([]types.Repo, 0)
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+
+		gitDir, ok := findGitDir(p)
+		if !ok {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			rel = filepath.Base(root)
+		}
+		pathWithNamespace := strings.TrimSuffix(filepath.ToSlash(rel), ".git")
+		if prev, ok := dirs[pathWithNamespace]; ok {
+			return fmt.Errorf("repositories %s and %s resolve to the same path %s", prev, p, pathWithNamespace)
+		}
+		dirs[pathWithNamespace] = p
+		repos = append(repos, types.NewRepo(path.Base(pathWithNamespace), pathWithNamespace, readDescription(gitDir), true))
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan source directory failed: %w", err)
+	}
+
+	l.mu.Lock()
+	l.dirs = dirs
+	l.mu.Unlock()
+	return repos, nil
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+// It returns the absolute path of the repository on disk.
+func (l *Local) GetSourceRepoAddr(pathWithNamespace string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dirs[pathWithNamespace]
+}
+
+// findGitDir reports whether dir is a repository and returns its git directory:
+// dir/.git for a working tree, or dir itself for a bare repository.
+func findGitDir(dir string) (string, bool) {
+	dotGit := filepath.Join(dir, ".git")
+	if _, err := os.Stat(dotGit); err == nil {
+		return dotGit, true
+	}
+	if isBare(dir) {
+		return dir, true
+	}
+	return "", false
+}
+
+// isBare checks for the minimal layout of a bare repository
+func isBare(dir string) bool {
+	if fi, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || fi.IsDir() {
+		return false
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if fi, err := os.Stat(filepath.Join(dir, sub)); err != nil || !fi.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+func readDescription(gitDir string) string {
+	b, err := os.ReadFile(filepath.Join(gitDir, "description"))
+	if err != nil {
+		return ""
+	}
+	desc := strings.TrimSpace(string(b))
+	if desc == defaultDescription {
+		return ""
+	}
+	return desc
+}
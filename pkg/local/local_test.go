@@ -0,0 +1,67 @@
+package local
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func gitInit(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"init", "--quiet"}, args...)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init %v failed: %v: %s", args, err, out)
+	}
+}
+
+func TestListRepos(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	root := t.TempDir()
+	gitInit(t, filepath.Join(root, "groupA", "api"))
+	gitInit(t, "--bare", filepath.Join(root, "groupB", "api.git"))
+	gitInit(t, "--bare", filepath.Join(root, "tools.git"))
+	if err := os.WriteFile(filepath.Join(root, "tools.git", "description"), []byte("Build tools\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Repositories nested inside another one are part of it and not listed.
+	gitInit(t, filepath.Join(root, "groupA", "api", "vendor", "lib"))
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocal(root)
+	repos, err := l.ListRepos(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, r := range repos {
+		got[r.GetPathWithNamespace()] = r.GetDesc()
+		if r.GetPath() != filepath.Base(r.GetPathWithNamespace()) {
+			t.Errorf("unexpected path %q for %q", r.GetPath(), r.GetPathWithNamespace())
+		}
+	}
+	want := map[string]string{
+		"groupA/api": "",
+		"groupB/api": "",
+		"tools":      "Build tools",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ListRepos() = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if desc, ok := got[k]; !ok || desc != v {
+			t.Errorf("repo %q: got desc %q (found %v), want %q", k, desc, ok, v)
+		}
+	}
+
+	if addr := l.GetSourceRepoAddr("groupB/api"); addr != filepath.Join(root, "groupB", "api.git") {
+		t.Errorf("GetSourceRepoAddr() = %q", addr)
+	}
+}
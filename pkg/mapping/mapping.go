@@ -0,0 +1,101 @@
+package mapping
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+// Namespace mapping modes
+const (
+	// Flat drops the source namespace and uses the repository path only
+	Flat = "flat"
+	// Encode folds the source namespace into the repository name, e.g. groupA/api becomes groupA__api
+	Encode = "encode"
+	// Preserve keeps the source namespace, the target must implement types.NestedTargetGit
+	Preserve = "preserve"
+)
+
+// DefaultSeparator joins namespace segments in Encode mode
+const DefaultSeparator = "__"
+
+// Mapper resolves the target path of source repositories
+type Mapper struct {
+	Mode      string
+	Separator string
+}
+
+func NewMapper(mode string) (*Mapper, error) {
+	switch mode {
+	case "":
+		mode = Flat
+	case Flat, Encode, Preserve:
+	default:
+		return nil, fmt.Errorf("invalid namespace mapping mode %q, must be one of %s, %s, %s", mode, Flat, Encode, Preserve)
+	}
+	return &Mapper{Mode: mode, Separator: DefaultSeparator}, nil
+}
+
+// Validate checks that the target can hold the paths produced by the mapper
+func (m *Mapper) Validate(target types.TargetGit) error {
+	if m.Mode != Preserve {
+		return nil
+	}
+	if nested, ok := target.(types.NestedTargetGit); ok && nested.SupportsNestedPaths() {
+		return nil
+	}
+	return fmt.Errorf("target %s cannot preserve namespaces, use the %s mapping mode instead", target.Name(), Encode)
+}
+
+// TargetPath returns the path of the repository on the target
+func (m *Mapper) TargetPath(repo types.Repo) string {
+	switch m.Mode {
+	case Encode:
+		return strings.ReplaceAll(strings.Trim(repo.GetPathWithNamespace(), "/"), "/", m.Separator)
+	case Preserve:
+		return strings.Trim(repo.GetPathWithNamespace(), "/")
+	default:
+		return repo.GetPath()
+	}
+}
+
+// Collision is a target path that more than one source repository maps to
+type Collision struct {
+	TargetPath string
+	Sources    []string
+}
+
+// FindCollisions returns the target paths claimed by more than one repository on target.
+// Paths are compared case-insensitively since most hosts treat them that way, after the
+// normalization of targets implementing types.NormalizingTargetGit.
+func (m *Mapper) FindCollisions(repos []types.Repo, target types.TargetGit) []Collision {
+	normalizer, _ := target.(types.NormalizingTargetGit)
+	targets := make(map[string]*Collision)
+	keys := make([]string, 0)
+	for _, repo := range repos {
+		targetPath := m.TargetPath(repo)
+		key := targetPath
+		if normalizer != nil {
+			key = normalizer.NormalizePath(key)
+		}
+		key = strings.ToLower(key)
+		c, ok := targets[key]
+		if !ok {
+			c = &Collision{TargetPath: targetPath}
+			targets[key] = c
+			keys = append(keys, key)
+		}
+		c.Sources = append(c.Sources, repo.GetPathWithNamespace())
+	}
+
+	collisions := make([]Collision, 0)
+	for _, key := range keys {
+		if c := targets[key]; len(c.Sources) > 1 {
+			sort.Strings(c.Sources)
+			collisions = append(collisions, *c)
+		}
+	}
+	return collisions
+}
@@ -0,0 +1,110 @@
+package mapping
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+type flatTarget struct{ types.TargetGit }
+
+func (flatTarget) Name() string { return "flat" }
+
+type nestedTarget struct{ types.TargetGit }
+
+func (nestedTarget) Name() string              { return "nested" }
+func (nestedTarget) SupportsNestedPaths() bool { return true }
+
+// foldingTarget folds runs of underscores and dashes into one dash like GitLab
+type foldingTarget struct{ types.TargetGit }
+
+func (foldingTarget) Name() string { return "folding" }
+func (foldingTarget) NormalizePath(path string) string {
+	return strings.Join(strings.FieldsFunc(path, func(r rune) bool { return r == '_' || r == '-' }), "-")
+}
+
+func TestTargetPath(t *testing.T) {
+	repo := types.NewRepo("api", "groupA/sub/api", "", true)
+	tests := map[string]string{
+		Flat:     "api",
+		Encode:   "groupA__sub__api",
+		Preserve: "groupA/sub/api",
+	}
+	for mode, want := range tests {
+		m, err := NewMapper(mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.TargetPath(repo); got != want {
+			t.Errorf("%s: TargetPath() = %q, want %q", mode, got, want)
+		}
+	}
+
+	if _, err := NewMapper("tree"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m, _ := NewMapper(Preserve)
+	if err := m.Validate(flatTarget{}); err == nil {
+		t.Error("expected preserve mode to be rejected for flat targets")
+	}
+	if err := m.Validate(nestedTarget{}); err != nil {
+		t.Errorf("unexpected error for nested target: %v", err)
+	}
+
+	m, _ = NewMapper(Encode)
+	if err := m.Validate(flatTarget{}); err != nil {
+		t.Errorf("unexpected error for encode mode: %v", err)
+	}
+}
+
+func TestFindCollisions(t *testing.T) {
+	repos := []types.Repo{
+		types.NewRepo("api", "groupA/api", "", true),
+		types.NewRepo("API", "groupB/API", "", true),
+		types.NewRepo("web", "groupA/web", "", true),
+	}
+
+	m, _ := NewMapper(Flat)
+	collisions := m.FindCollisions(repos, flatTarget{})
+	if len(collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %+v", collisions)
+	}
+	if c := collisions[0]; c.TargetPath != "api" || len(c.Sources) != 2 || c.Sources[0] != "groupA/api" || c.Sources[1] != "groupB/API" {
+		t.Errorf("unexpected collision: %+v", c)
+	}
+
+	m, _ = NewMapper(Encode)
+	if collisions := m.FindCollisions(repos, flatTarget{}); len(collisions) != 0 {
+		t.Errorf("expected no collisions in encode mode, got %+v", collisions)
+	}
+}
+
+func TestFindCollisionsNormalized(t *testing.T) {
+	repos := []types.Repo{
+		types.NewRepo("my_api", "g/my_api", "", true),
+		types.NewRepo("my-api", "g/my-api", "", true),
+	}
+	m, _ := NewMapper(Flat)
+	if collisions := m.FindCollisions(repos, flatTarget{}); len(collisions) != 0 {
+		t.Errorf("expected no collisions without normalization, got %+v", collisions)
+	}
+	collisions := m.FindCollisions(repos, foldingTarget{})
+	if len(collisions) != 1 || collisions[0].TargetPath != "my_api" || len(collisions[0].Sources) != 2 {
+		t.Errorf("expected my_api and my-api to collide, got %+v", collisions)
+	}
+
+	// Encoded namespaces fold into the same name as a dashed repo
+	repos = []types.Repo{
+		types.NewRepo("b", "a/b", "", true),
+		types.NewRepo("a-b", "a-b", "", true),
+	}
+	m, _ = NewMapper(Encode)
+	collisions = m.FindCollisions(repos, foldingTarget{})
+	if len(collisions) != 1 || collisions[0].TargetPath != "a__b" {
+		t.Errorf("expected a__b and a-b to collide, got %+v", collisions)
+	}
+}
@@ -0,0 +1,87 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/k8scat/mirror-git-go/pkg/mapping"
+)
+
+// Plan is what a run would do, computed without cloning or pushing
+type Plan struct {
+	Source string
+	Target string
+	// Create are the repos missing on the target
+	Create []Item
+	// Update are the repos that already exist on the target and would be pushed to
+	Update []Item
+	// Errors are the repos whose existence on the target could not be checked
+	Errors     []Item
+	Collisions []mapping.Collision
+	Skipped    []Skipped
+}
+
+// Item is a source repo and the target path it maps to
+type Item struct {
+	Repo       string
+	TargetPath string
+	Private    bool
+	Error      string
+}
+
+// Skipped is a source repo that would not be mirrored
+type Skipped struct {
+	Repo   string
+	Reason string
+}
+
+// Sort orders every section by source repo so that plans can be diffed
+func (p *Plan) Sort() {
+	for _, items := range [][]Item{p.Create, p.Update, p.Errors} {
+		sort.Slice(items, func(i, j int) bool { return items[i].Repo < items[j].Repo })
+	}
+	sort.Slice(p.Skipped, func(i, j int) bool { return p.Skipped[i].Repo < p.Skipped[j].Repo })
+}
+
+// Print writes the plan in a human readable form
+func (p *Plan) Print(w io.Writer) {
+	fmt.Fprintf(w, "Plan for mirroring %s to %s\n", p.Source, p.Target)
+
+	fmt.Fprintf(w, "\nRepos to create (%d):\n", len(p.Create))
+	for _, item := range p.Create {
+		fmt.Fprintf(w, "  + %s -> %s (%s)\n", item.Repo, item.TargetPath, visibility(item.Private))
+	}
+
+	fmt.Fprintf(w, "\nRepos to update (%d):\n", len(p.Update))
+	for _, item := range p.Update {
+		fmt.Fprintf(w, "  ~ %s -> %s\n", item.Repo, item.TargetPath)
+	}
+
+	fmt.Fprintf(w, "\nName collisions (%d):\n", len(p.Collisions))
+	for _, c := range p.Collisions {
+		fmt.Fprintf(w, "  ! %s <- %v\n", c.TargetPath, c.Sources)
+	}
+
+	fmt.Fprintf(w, "\nSkipped repos (%d):\n", len(p.Skipped))
+	for _, s := range p.Skipped {
+		fmt.Fprintf(w, "  - %s: %s\n", s.Repo, s.Reason)
+	}
+
+	if len(p.Errors) > 0 {
+		fmt.Fprintf(w, "\nRepos that could not be checked (%d):\n", len(p.Errors))
+		for _, item := range p.Errors {
+			fmt.Fprintf(w, "  ? %s -> %s: %s\n", item.Repo, item.TargetPath, item.Error)
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d to create, %d to update, %d collisions, %d skipped, %d errors\n",
+		len(p.Create), len(p.Update), len(p.Collisions), len(p.Skipped), len(p.Errors))
+}
+
+func visibility(private bool) string {
+	if private {
+		return "private"
+	}
+	return "public"
+}
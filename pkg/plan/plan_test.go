@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k8scat/mirror-git-go/pkg/mapping"
+)
+
+func TestPrint(t *testing.T) {
+	p := &Plan{
+		Source: "gitlab",
+		Target: "github",
+		Create: []Item{
+			{Repo: "group/web", TargetPath: "web"},
+			{Repo: "group/api", TargetPath: "api", Private: true},
+		},
+		Update:     []Item{{Repo: "group/docs", TargetPath: "docs"}},
+		Collisions: []mapping.Collision{{TargetPath: "api", Sources: []string{"group/api", "other/api"}}},
+		Skipped:    []Skipped{{Repo: "group/old", Reason: "archived"}},
+	}
+	p.Sort()
+
+	var b strings.Builder
+	p.Print(&b)
+	out := b.String()
+	for _, want := range []string{
+		"Repos to create (2):\n  + group/api -> api (private)\n  + group/web -> web (public)\n",
+		"Repos to update (1):\n  ~ group/docs -> docs\n",
+		"  ! api <- [group/api other/api]\n",
+		"  - group/old: archived\n",
+		"2 to create, 1 to update, 1 collisions, 1 skipped, 0 errors\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan misses %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "could not be checked") {
+		t.Errorf("plan lists an empty error section:\n%s", out)
+	}
+}
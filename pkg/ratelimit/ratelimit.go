@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetries is how often a rate limited request is sent again after waiting
+const maxRetries = 3
+
+// defaultWait is how long to wait after a rate limited response without a reset time
+const defaultWait = time.Minute
+
+// DefaultTimeout is the Timeout of a Transport created by New
+const DefaultTimeout = 60 * time.Second
+
+// Transport is an http.RoundTripper that spaces requests to at most RPS per second
+// and, once a response reports the rate limit as exhausted, blocks every request
+// until the limit is reset. A Transport is meant to be shared by all clients of a provider.
+type Transport struct {
+	Base http.RoundTripper
+	// Timeout limits each attempt from the moment it may be sent until its body is closed, 0 for
+	// no limit. Clients must not set http.Client.Timeout, which would also cut short the wait
+	// for the rate limit to reset.
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// interval is the minimum time between two requests, 0 for no limit
+	interval time.Duration
+	// next is the earliest time the next request may be sent
+	next time.Time
+	// blockedUntil is the time the rate limit is reset after it was exhausted
+	blockedUntil time.Time
+}
+
+// New creates a Transport sending at most rps requests per second through base.
+// A rps of 0 or less only honors the rate limit headers.
+func New(base http.RoundTripper, rps float64) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{Base: base, Timeout: DefaultTimeout}
+	if rps > 0 {
+		t.interval = time.Duration(float64(time.Second) / rps)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.send(req)
+		if err != nil {
+			return nil, err
+		}
+
+		limited, until := t.update(resp)
+		if !limited || attempt >= maxRetries {
+			return resp, nil
+		}
+
+		// Send the request again once the limit is reset, if its body can be replayed
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+		slog.Warn("rate limited, waiting for reset", "host", req.URL.Host, "until", until.Format(time.RFC3339))
+		resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// send sends a single attempt of req under the Timeout
+func (t *Transport) send(req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of an attempt once its body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// wait blocks until the request may be sent or its context is done
+func (t *Transport) wait(req *http.Request) error {
+	t.mu.Lock()
+	now := time.Now()
+	at := now
+	if t.blockedUntil.After(at) {
+		at = t.blockedUntil
+	}
+	if t.next.After(at) {
+		at = t.next
+	}
+	t.next = at.Add(t.interval)
+	t.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// update records the rate limit reported by resp. It reports whether the request
+// was rejected by the rate limit, and the time the limit is reset.
+func (t *Transport) update(resp *http.Response) (bool, time.Time) {
+	now := time.Now()
+	h := resp.Header
+
+	var until time.Time
+	if d, ok := parseRetryAfter(h.Get("Retry-After"), now); ok {
+		until = now.Add(d)
+	} else if remaining(h) == 0 {
+		until = resetTime(h, now)
+	}
+
+	// GitHub answers an exhausted limit with 403, secondary rate limits come with Retry-After
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode == http.StatusForbidden && !until.IsZero())
+	if limited && until.IsZero() {
+		until = now.Add(defaultWait)
+	}
+	if until.IsZero() {
+		return false, until
+	}
+
+	t.mu.Lock()
+	if until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	t.mu.Unlock()
+	return limited, until
+}
+
+// remaining returns the requests left in the current window, or -1 if unknown.
+// GitHub and Gitee send X-RateLimit-*, GitLab sends RateLimit-*.
+func remaining(h http.Header) int {
+	for _, name := range []string{"X-RateLimit-Remaining", "RateLimit-Remaining"} {
+		if v := h.Get(name); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				return n
+			}
+		}
+	}
+	return -1
+}
+
+// resetTime returns when the current window ends. The reset headers are either
+// a Unix timestamp or, as in the IETF draft, the seconds until the reset.
+func resetTime(h http.Header, now time.Time) time.Time {
+	for _, name := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		v := h.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		// Values below a day in seconds are delays, anything else is a timestamp
+		if n < 24*60*60 {
+			return now.Add(time.Duration(n) * time.Second)
+		}
+		return time.Unix(n, 0)
+	}
+	return now.Add(defaultWait)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return at.Sub(now), true
+	}
+	return 0, false
+}
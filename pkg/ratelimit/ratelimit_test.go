@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d got body %q", calls, body)
+		}
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: New(nil, 0)}
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || calls != 2 {
+		t.Fatalf("got status %d after %d calls, want 201 after 2", resp.StatusCode, calls)
+	}
+}
+
+func TestBlockUntilReset(t *testing.T) {
+	var first sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		first.Do(func() {
+			// GitLab style headers, with the reset given in seconds
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", "1")
+		})
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: New(nil, 0)}
+	start := time.Now()
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("second request sent after %s, want it to wait for the reset", elapsed)
+	}
+
+	// Waiting workers give up when their context is done
+	tr := New(nil, 0)
+	tr.blockedUntil = time.Now().Add(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := (&http.Client{Transport: tr}).Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch {
+		case r.URL.Path == "/slow":
+			time.Sleep(500 * time.Millisecond)
+		case calls == 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	// Waiting for the reset does not count towards the timeout of the attempt
+	tr := New(nil, 0)
+	tr.Timeout = 200 * time.Millisecond
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request waiting longer than the timeout for the reset failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "ok" || calls != 2 {
+		t.Errorf("got %q, %v after %d calls, want ok after 2", body, err, calls)
+	}
+
+	if _, err := client.Get(srv.URL + "/slow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("slow request = %v, want deadline exceeded", err)
+	}
+}
+
+func TestRequestsPerSecond(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: New(nil, 20)}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("5 requests at 20 rps took %s, want at least 200ms", elapsed)
+	}
+}
+
+func TestResetTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	h := http.Header{}
+	h.Set("X-RateLimit-Reset", "1700000600")
+	if got := resetTime(h, now); !got.Equal(time.Unix(1700000600, 0)) {
+		t.Errorf("resetTime() = %s, want the Unix timestamp", got)
+	}
+	h = http.Header{}
+	h.Set("RateLimit-Reset", "30")
+	if got := resetTime(h, now); !got.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("resetTime() = %s, want 30s from now", got)
+	}
+}
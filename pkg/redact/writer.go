@@ -0,0 +1,52 @@
+package redact
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Writer masks secrets in line oriented output, such as that of git, before writing it on.
+// Output is held back until the end of a line, call Flush to write an unterminated last line.
+type Writer struct {
+	w io.Writer
+	r *Redactor
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewWriter wraps w to mask secrets with the default redactor
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, r: std}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	// git ends progress lines with \r
+	end := bytes.LastIndexAny(w.buf, "\r\n")
+	if end < 0 {
+		return len(p), nil
+	}
+	if _, err := io.WriteString(w.w, w.r.String(string(w.buf[:end+1]))); err != nil {
+		return 0, err
+	}
+	w.buf = append(w.buf[:0], w.buf[end+1:]...)
+	return len(p), nil
+}
+
+// Flush writes the output held back since the last line end
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(w.w, w.r.String(string(w.buf)))
+	w.buf = w.buf[:0]
+	return err
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// WriteJUnit saves the report as JUnit XML with one testcase per repo and target
+func (r *Report) WriteJUnit(path string) error {
+	r.mu.Lock()
+	suite := junitTestSuite{
+		Name:      fmt.Sprintf("mirror %s to %s", r.Source, r.Target),
+		Tests:     r.Total,
+		Failures:  r.Failed,
+		Skipped:   r.Skipped,
+		Time:      seconds(r.FinishedAt.Sub(r.StartedAt)),
+		Timestamp: r.StartedAt.Format(time.RFC3339),
+		Cases:     make([]junitTestCase, 0, len(r.Repos)),
+	}
+	for _, result := range r.Repos {
+		tc := junitTestCase{
+			Name:      result.Repo,
+			ClassName: "mirror." + r.Source,
+			Time:      seconds(time.Duration(result.DurationMs) * time.Millisecond),
+		}
+		// Group the testcases of a run with several targets by target
+		if result.Target != "" {
+			tc.ClassName += "." + result.Target
+		}
+		switch result.Status {
+		case StatusFailed:
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%s failed", result.Phase),
+				Type:    result.Phase,
+				Text:    result.Error,
+			}
+		case StatusSkipped:
+			tc.Skipped = &junitSkipped{Message: "skipped"}
+			if result.Reason != "" {
+				tc.Skipped.Message = "skipped: " + result.Reason
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	r.mu.Unlock()
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal junit report failed: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write junit report failed: %w", err)
+	}
+	return nil
+}
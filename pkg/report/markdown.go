@@ -0,0 +1,86 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Markdown renders the report as a summary followed by a table of the repos,
+// failed repos first
+func (r *Report) Markdown() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Mirror %s → %s\n\n", r.Source, r.Target)
+	fmt.Fprintf(&b, "%d repos: %d succeeded, %d failed, %d skipped in %s\n\n",
+		r.Total, r.Succeeded, r.Failed, r.Skipped, r.FinishedAt.Sub(r.StartedAt).Round(time.Second))
+	if r.Interrupted {
+		fmt.Fprintf(&b, "**Interrupted**, %d repos were not started\n\n", r.NotStarted)
+	}
+	if len(r.Repos) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("| Repo | Target | Status | Phase | Duration | Error |\n")
+	b.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	for _, status := range []string{StatusFailed, StatusSuccess, StatusSkipped} {
+		for _, result := range r.Repos {
+			if result.Status != status {
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				markdownCell(result.Repo),
+				markdownCell(targetCell(result)),
+				statusEmoji(result.Status)+" "+result.Status,
+				result.Phase,
+				(time.Duration(result.DurationMs) * time.Millisecond).Round(time.Second),
+				markdownCell(result.Error+result.Reason),
+			)
+		}
+	}
+	return b.String()
+}
+
+// AppendMarkdown appends the Markdown summary to path, which is how
+// CI job summary files such as $GITHUB_STEP_SUMMARY are meant to be written
+func (r *Report) AppendMarkdown(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open summary failed: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(r.Markdown() + "\n"); err != nil {
+		return fmt.Errorf("write summary failed: %w", err)
+	}
+	return nil
+}
+
+// targetCell names the target provider along with the path when a run has several targets
+func targetCell(result RepoResult) string {
+	if result.Target == "" {
+		return result.TargetPath
+	}
+	return result.Target + ":" + result.TargetPath
+}
+
+func statusEmoji(status string) string {
+	switch status {
+	case StatusSuccess:
+		return "✅"
+	case StatusFailed:
+		return "❌"
+	default:
+		return "⏭️"
+	}
+}
+
+// markdownCell keeps a value on one line and escapes table separators
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,195 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/redact"
+)
+
+// Phases of mirroring a repo
+const (
+	PhaseClone  = "clone"
+	PhaseExists = "exists"
+	PhaseCreate = "create"
+	PhasePush   = "push"
+)
+
+// Repo statuses
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Exit codes of a run with failed repos
+const (
+	ExitPartialFailure = 2
+	ExitTotalFailure   = 3
+)
+
+// PhaseError is an error in a phase of mirroring a repo
+type PhaseError struct {
+	Phase string
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	return e.Phase + " failed: " + e.Err.Error()
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// MirrorError is returned when some or all repos of a run failed
+type MirrorError struct {
+	Failed int
+	Total  int
+}
+
+func (e *MirrorError) Error() string {
+	return fmt.Sprintf("%d of %d repos failed to mirror", e.Failed, e.Total)
+}
+
+// ExitCode distinguishes runs where every repo failed from partial failures
+func (e *MirrorError) ExitCode() int {
+	if e.Failed >= e.Total {
+		return ExitTotalFailure
+	}
+	return ExitPartialFailure
+}
+
+// RepoResult is the outcome of mirroring a single repo
+type RepoResult struct {
+	// Repo is the source path with namespace
+	Repo       string `json:"repo"`
+	TargetPath string `json:"target_path"`
+	// Target is the provider the repo was mirrored to when a run has several targets
+	Target string `json:"target,omitempty"`
+	Status string `json:"status"`
+	// Phase is the phase that failed
+	Phase string `json:"phase,omitempty"`
+	// Reason is why a repo was skipped
+	Reason     string    `json:"reason,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitzero"`
+	DurationMs int64     `json:"duration_ms"`
+	// Bytes is the size of the clone on disk
+	Bytes int64 `json:"bytes"`
+	// Retries is the number of retries after transient errors
+	Retries int `json:"retries"`
+}
+
+// Report is the machine readable outcome of a run
+type Report struct {
+	Source     string    `json:"source"`
+	Target     string    `json:"target"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Total      int       `json:"total"`
+	Succeeded  int       `json:"succeeded"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	// NotStarted is the number of repos left when the run was interrupted
+	NotStarted  int          `json:"not_started,omitempty"`
+	Interrupted bool         `json:"interrupted,omitempty"`
+	Repos       []RepoResult `json:"repos"`
+
+	mu sync.Mutex
+}
+
+func New(source, target string) *Report {
+	return &Report{
+		Source:    source,
+		Target:    target,
+		StartedAt: time.Now(),
+		Repos:     make([]RepoResult, 0),
+	}
+}
+
+// Add records the result of a repo with secrets masked, it is safe for concurrent use
+func (r *Report) Add(result RepoResult) {
+	result.Error = redact.String(result.Error)
+	result.Reason = redact.String(result.Reason)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Repos = append(r.Repos, result)
+	r.Total++
+	switch result.Status {
+	case StatusSuccess:
+		r.Succeeded++
+	case StatusFailed:
+		r.Failed++
+	case StatusSkipped:
+		r.Skipped++
+	}
+}
+
+// Interrupt marks the run as stopped before notStarted repos were scheduled
+func (r *Report) Interrupt(notStarted int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Interrupted = true
+	r.NotStarted = notStarted
+}
+
+// Finish stamps the end of the run and returns a *MirrorError if any repo failed
+func (r *Report) Finish() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.FinishedAt = time.Now()
+	if r.Failed == 0 {
+		return nil
+	}
+	return &MirrorError{Failed: r.Failed, Total: r.Succeeded + r.Failed}
+}
+
+// FailedRepos returns the source paths of the repos that failed on any target
+func (r *Report) FailedRepos() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repos := make([]string, 0, r.Failed)
+	for _, result := range r.Repos {
+		if result.Status == StatusFailed && !slices.Contains(repos, result.Repo) {
+			repos = append(repos, result.Repo)
+		}
+	}
+	return repos
+}
+
+// Write saves the report as JSON
+func (r *Report) Write(path string) error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal report failed: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write report failed: %w", err)
+	}
+	return nil
+}
+
+// Load reads a report written by Write
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read report failed: %w", err)
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("parse report %s failed: %w", path, err)
+	}
+	return r, nil
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	r := New("github", "gitlab")
+	r.Add(RepoResult{Repo: "group/api", Status: StatusSuccess, Bytes: 1024})
+	r.Add(RepoResult{Repo: "group/web", Status: StatusFailed, Phase: PhasePush, Error: "push failed: exit status 1"})
+	r.Add(RepoResult{Repo: "group/docs", Status: StatusSkipped})
+
+	err := r.Finish()
+	var mirrorErr *MirrorError
+	if !errors.As(err, &mirrorErr) {
+		t.Fatalf("Finish() = %v, want *MirrorError", err)
+	}
+	if mirrorErr.ExitCode() != ExitPartialFailure {
+		t.Errorf("ExitCode() = %d, want %d", mirrorErr.ExitCode(), ExitPartialFailure)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := r.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Total != 3 || loaded.Succeeded != 1 || loaded.Failed != 1 || loaded.Skipped != 1 {
+		t.Errorf("unexpected counts: %+v", loaded)
+	}
+	if failed := loaded.FailedRepos(); len(failed) != 1 || failed[0] != "group/web" {
+		t.Errorf("FailedRepos() = %v", failed)
+	}
+}
+
+func TestTotalFailure(t *testing.T) {
+	r := New("github", "gitlab")
+	r.Add(RepoResult{Repo: "group/api", Status: StatusFailed})
+	r.Add(RepoResult{Repo: "group/docs", Status: StatusSkipped})
+
+	var mirrorErr *MirrorError
+	if err := r.Finish(); !errors.As(err, &mirrorErr) || mirrorErr.ExitCode() != ExitTotalFailure {
+		t.Fatalf("Finish() = %v, want total failure", err)
+	}
+
+	if err := New("github", "gitlab").Finish(); err != nil {
+		t.Errorf("Finish() without failures = %v, want nil", err)
+	}
+}
+
+func TestPhaseError(t *testing.T) {
+	cause := errors.New("exit status 128")
+	err := fmt.Errorf("mirror: %w", &PhaseError{Phase: PhaseClone, Err: cause})
+
+	var phaseErr *PhaseError
+	if !errors.As(err, &phaseErr) || phaseErr.Phase != PhaseClone {
+		t.Fatalf("errors.As() failed for %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("PhaseError must unwrap to its cause")
+	}
+	if got := phaseErr.Error(); got != "clone failed: exit status 128" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func sampleReport() *Report {
+	r := New("github", "gitlab")
+	r.Add(RepoResult{Repo: "group/api", TargetPath: "api", Status: StatusSuccess, DurationMs: 1500})
+	r.Add(RepoResult{Repo: "group/web", TargetPath: "web", Status: StatusFailed, Phase: PhasePush, Error: "push failed: a | b\nremote rejected"})
+	r.Add(RepoResult{Repo: "group/docs", TargetPath: "docs", Status: StatusSkipped})
+	r.Finish()
+	return r
+}
+
+func TestWriteJUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := sampleReport().WriteJUnit(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || len(suite.Cases) != 3 {
+		t.Fatalf("unexpected suite: %+v", suite)
+	}
+	if tc := suite.Cases[1]; tc.Failure == nil || tc.Failure.Type != PhasePush || !strings.Contains(tc.Failure.Text, "remote rejected") {
+		t.Errorf("unexpected failed testcase: %+v", tc)
+	}
+	if tc := suite.Cases[0]; tc.Time != "1.500" || tc.Failure != nil {
+		t.Errorf("unexpected successful testcase: %+v", tc)
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	md := sampleReport().Markdown()
+	if !strings.Contains(md, "3 repos: 1 succeeded, 1 failed, 1 skipped") {
+		t.Errorf("missing summary line:\n%s", md)
+	}
+	if !strings.Contains(md, `| group/web | web | ❌ failed | push | 0s | push failed: a \| b remote rejected |`) {
+		t.Errorf("failed repo row not escaped:\n%s", md)
+	}
+	if strings.Index(md, "group/web") > strings.Index(md, "group/api") {
+		t.Errorf("failed repos must be listed first:\n%s", md)
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.md")
+	os.WriteFile(path, []byte("# Job\n"), 0644)
+	if err := sampleReport().AppendMarkdown(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "# Job\n## Mirror github → gitlab") {
+		t.Errorf("summary not appended:\n%s", data)
+	}
+}
+
+func TestMultipleTargets(t *testing.T) {
+	r := New("github", "gitlab,gitee")
+	r.Add(RepoResult{Repo: "group/api", TargetPath: "api", Target: "gitlab", Status: StatusFailed, Phase: PhasePush})
+	r.Add(RepoResult{Repo: "group/api", TargetPath: "api", Target: "gitee", Status: StatusFailed, Phase: PhaseCreate})
+	r.Finish()
+
+	if got := r.FailedRepos(); len(got) != 1 || got[0] != "group/api" {
+		t.Errorf("FailedRepos() = %v, want [group/api]", got)
+	}
+	if md := r.Markdown(); !strings.Contains(md, "| group/api | gitee:api | ❌ failed | create |") {
+		t.Errorf("target missing from row:\n%s", md)
+	}
+
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := r.WriteJUnit(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatal(err)
+	}
+	if tc := suites.Suites[0].Cases[1]; tc.ClassName != "mirror.github.gitee" {
+		t.Errorf("classname = %q, want mirror.github.gitee", tc.ClassName)
+	}
+}
@@ -0,0 +1,143 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+// Policy is a capped exponential backoff with jitter
+type Policy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// InitialDelay is the delay before the first retry, doubled for every further retry
+	InitialDelay time.Duration
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+}
+
+var DefaultPolicy = Policy{
+	MaxRetries:   3,
+	InitialDelay: 2 * time.Second,
+	MaxDelay:     time.Minute,
+}
+
+// Delay returns the delay before the given retry, counting from 1.
+// Half of the delay is random so that workers failing together do not retry together.
+func (p Policy) Delay(retry int) time.Duration {
+	d := p.InitialDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable
+// or the retries are exhausted. It returns the number of retries made.
+func (p Policy) Do(ctx context.Context, op string, fn func() error) (int, error) {
+	retries := 0
+	for {
+		err := fn()
+		if err == nil || retries >= p.MaxRetries || !IsRetryable(err) {
+			return retries, err
+		}
+		retries++
+
+		delay := p.Delay(retries)
+		slog.Warn("transient error, retrying", "op", op, "error", err, "retry", retries, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, err
+		case <-timer.C:
+		}
+	}
+}
+
+// fatalMessages are git and API errors that fail the same way on every attempt.
+// They are checked before retryableMessages, as a rejected push also ends the connection.
+var fatalMessages = []string{
+	"authentication failed",
+	"permission denied",
+	"could not read username",
+	"repository not found",
+	"does not appear to be a git repository",
+	"protected branch",
+	"pre-receive hook declined",
+	"returned error: 401",
+	"returned error: 403",
+	"returned error: 404",
+}
+
+// retryableMessages are git errors of dropped or overloaded connections
+var retryableMessages = []string{
+	"early eof",
+	"rpc failed",
+	"remote end hung up unexpectedly",
+	"unexpected disconnect",
+	"connection reset",
+	"connection refused",
+	"connection timed out",
+	"operation timed out",
+	"i/o timeout",
+	"tls handshake timeout",
+	"gnutls_handshake() failed",
+	"could not resolve host",
+	"returned error: 429",
+	"returned error: 5",
+	"http 5",
+}
+
+// IsRetryable reports whether err is transient: a network error, an HTTP 429 or 5xx response,
+// or a git transfer that broke off. Errors that are not recognized are not retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *types.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, s := range fatalMessages {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	for _, s := range retryableMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"too many requests", types.NewStatusError(429, errors.New("list repos failed")), true},
+		{"bad gateway", fmt.Errorf("check repo: %w", types.NewStatusError(502, errors.New("bad gateway"))), true},
+		{"unauthorized", types.NewStatusError(401, errors.New("unauthorized")), false},
+		{"forbidden", types.NewStatusError(403, errors.New("forbidden")), false},
+		{"not found", types.NewStatusError(404, errors.New("not found")), false},
+		{"connection reset", fmt.Errorf("failed to send request: %w", syscall.ECONNRESET), true},
+		{"unexpected eof", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF), true},
+		{"cancelled", fmt.Errorf("clone: %w", context.Canceled), false},
+		{"git early eof", errors.New("git clone failed: exit status 128: fatal: early EOF"), true},
+		{"git rpc failed", errors.New("git push failed: exit status 1: error: RPC failed; HTTP 502 curl 22"), true},
+		{"git auth", errors.New("git clone failed: exit status 128: fatal: Authentication failed for 'https://example.com/a.git/'"), false},
+		{"git not found", errors.New("git clone failed: exit status 128: remote: Repository not found."), false},
+		{"protected branch", errors.New("git push failed: exit status 1: remote: GitLab: You are not allowed to force push code to a protected branch on this project.\nfatal: the remote end hung up unexpectedly"), false},
+		{"unknown", errors.New("failed to decode response"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelay(t *testing.T) {
+	p := Policy{MaxRetries: 10, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	for retry, max := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		for range 20 {
+			if d := p.Delay(retry); d < max/2 || d > max {
+				t.Errorf("Delay(%d) = %s, want between %s and %s", retry, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	p := Policy{MaxRetries: 3, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	transient := types.NewStatusError(503, errors.New("unavailable"))
+
+	calls := 0
+	retries, err := p.Do(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || retries != 2 || calls != 3 {
+		t.Errorf("got retries=%d calls=%d err=%v, want success after 2 retries", retries, calls, err)
+	}
+
+	calls = 0
+	retries, err = p.Do(context.Background(), "test", func() error {
+		calls++
+		return transient
+	})
+	if !errors.Is(err, transient) || retries != 3 || calls != 4 {
+		t.Errorf("got retries=%d calls=%d err=%v, want retries exhausted", retries, calls, err)
+	}
+
+	calls = 0
+	fatal := types.NewStatusError(403, errors.New("forbidden"))
+	retries, err = p.Do(context.Background(), "test", func() error {
+		calls++
+		return fatal
+	})
+	if !errors.Is(err, fatal) || retries != 0 || calls != 1 {
+		t.Errorf("got retries=%d calls=%d err=%v, want no retry of a fatal error", retries, calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p.InitialDelay, p.MaxDelay = time.Hour, time.Hour
+	retries, err = p.Do(ctx, "test", func() error { return transient })
+	if !errors.Is(err, transient) || retries != 1 {
+		t.Errorf("got retries=%d err=%v, want to stop waiting once cancelled", retries, err)
+	}
+}
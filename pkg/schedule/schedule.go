@@ -0,0 +1,184 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// everyPrefix starts a schedule given as a fixed interval, e.g. @every 6h
+const everyPrefix = "@every "
+
+// maxSearch bounds the search for the next time of a cron expression that never matches, e.g. 30 2 31 2 *
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is a cron expression or a fixed interval
+type Schedule struct {
+	every time.Duration
+	// fields of a cron expression: minute, hour, day of month, month, day of week
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record unrestricted day fields, as cron matches either restricted day field
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fieldBounds = []bounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Parse parses a standard five field cron expression (minute hour day-of-month month day-of-week),
+// one of the descriptors such as @daily, or a fixed interval such as @every 6h
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, everyPrefix); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if every < time.Minute {
+			return nil, fmt.Errorf("invalid schedule %q: interval must be at least 1m", spec)
+		}
+		return &Schedule{every: every}, nil
+	}
+	if expr, ok := descriptors[spec]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(fieldBounds) {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		b, err := parseField(field, fieldBounds[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// parseField parses a comma separated list of *, values, ranges and steps into a bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, b.name)
+			}
+			step = n
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(loStr, b); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(hiStr, b); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = b.max
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q in %s field", rng, b.name)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	n, err := strconv.Atoi(s)
+	// Sunday may be given as 7
+	if err == nil && b.name == "day of week" && n == 7 {
+		n = 0
+	}
+	if err != nil || n < b.min || n > b.max {
+		return 0, fmt.Errorf("invalid value %q in %s field, must be between %d and %d", s, b.name, b.min, b.max)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t that the schedule fires
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	// Step in the wall clock time of t, truncating in absolute time is off by the zone offset
+	// in zones that are not a whole number of hours from UTC, e.g. Asia/Kolkata
+	next := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+	limit := next.Add(maxSearch)
+	for next.Before(limit) {
+		switch {
+		case s.month&(1<<uint(next.Month())) == 0:
+			next = later(next, time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location()))
+		case !s.dayMatches(next):
+			next = later(next, time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location()))
+		case s.hour&(1<<uint(next.Hour())) == 0:
+			next = later(next, time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location()))
+		case s.minute&(1<<uint(next.Minute())) == 0:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// later returns next, or an hour after cur if a clock set back for the end of daylight saving time
+// made next no later than cur
+func later(cur, next time.Time) time.Time {
+	if next.After(cur) {
+		return next
+	}
+	return cur.Add(time.Hour)
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
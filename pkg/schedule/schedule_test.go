@@ -0,0 +1,85 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 1-5", time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"0 9 1,15 * *", time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC)},
+		// Restricted day of month and day of week match either
+		{"0 0 13 * 5", time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 6h", from.Add(6 * time.Hour)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next() = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+
+	s, _ := Parse("0 0 31 2 *")
+	if got := s.Next(from); !got.IsZero() {
+		t.Errorf("impossible schedule fired at %s", got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@every 10s", "@every soon", "@sometimes"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+func TestNextTimeZones(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available:", err)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		// UTC+5:30 is not a whole number of hours
+		{"0 11 * * *", time.Date(2024, 1, 31, 10, 17, 30, 0, kolkata), time.Date(2024, 1, 31, 11, 0, 0, 0, kolkata)},
+		{"@daily", time.Date(2024, 1, 31, 10, 17, 30, 0, kolkata), time.Date(2024, 2, 1, 0, 0, 0, 0, kolkata)},
+		{"@hourly", time.Date(2024, 1, 31, 10, 17, 30, 0, kolkata), time.Date(2024, 1, 31, 11, 0, 0, 0, kolkata)},
+		// Clocks go from 2:00 EST to 3:00 EDT on 10 March 2024
+		{"0 * * * *", time.Date(2024, 3, 10, 1, 30, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+		{"0 3 * * *", time.Date(2024, 3, 9, 12, 0, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+		// and back from 2:00 EDT to 1:00 EST on 3 November 2024
+		{"0 2 * * *", time.Date(2024, 11, 3, 0, 30, 0, 0, newYork), time.Date(2024, 11, 3, 2, 0, 0, 0, newYork)},
+		{"@daily", time.Date(2024, 11, 2, 23, 0, 0, 0, newYork), time.Date(2024, 11, 3, 0, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
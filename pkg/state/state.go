@@ -0,0 +1,197 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/k8scat/mirror-git-go/pkg/redact"
+)
+
+// Repo statuses
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// State is the persisted state of the latest run
+type State struct {
+	RunID      string    `json:"run_id"`
+	Source     string    `json:"source"`
+	Target     string    `json:"target"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	// Completed is false when the run was interrupted before every repo was processed
+	Completed bool `json:"completed"`
+	// Repos is keyed by the source path with namespace and kept across runs
+	Repos map[string]*Repo `json:"repos"`
+}
+
+// Repo is the state of a single repository
+type Repo struct {
+	// RunID is the run that last processed the repo
+	RunID      string    `json:"run_id"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitzero"`
+	// LastSuccessAt and Refs (ref name to object hash) describe the last successful mirror
+	LastSuccessAt time.Time         `json:"last_success_at,omitzero"`
+	Refs          map[string]string `json:"refs,omitempty"`
+}
+
+// Store persists the run state to a JSON file after every change,
+// so that an interrupted run can be resumed
+type Store struct {
+	path string
+
+	mu    sync.Mutex
+	state *State
+}
+
+// Open loads the state file at path, a missing file is an empty state
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, state: &State{Repos: make(map[string]*Repo)}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state failed: %w", err)
+	}
+	if err := json.Unmarshal(data, s.state); err != nil {
+		return nil, fmt.Errorf("parse state %s failed: %w", path, err)
+	}
+	if s.state.Repos == nil {
+		s.state.Repos = make(map[string]*Repo)
+	}
+	return s, nil
+}
+
+// Path returns the location of the state file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// BeginRun starts a new run, or continues the previous one when resume is set and
+// it was interrupted with the same source and target. It reports whether it resumed.
+func (s *Store) BeginRun(source, target string, resume bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resume && s.state.RunID != "" && !s.state.Completed && s.state.Source == source && s.state.Target == target {
+		return true, s.save()
+	}
+
+	now := time.Now()
+	s.state.RunID = now.Format("20060102150405.000000")
+	s.state.Source = source
+	s.state.Target = target
+	s.state.StartedAt = now
+	s.state.FinishedAt = time.Time{}
+	s.state.Completed = false
+	return false, s.save()
+}
+
+// EndRun marks the current run as finished. completed is false when
+// the run stopped before processing every repo.
+func (s *Store) EndRun(completed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.FinishedAt = time.Now()
+	s.state.Completed = completed
+	return s.save()
+}
+
+// Succeeded reports whether repo was mirrored successfully in the current run
+func (s *Store) Succeeded(repo string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.state.Repos[repo]
+	return ok && r.RunID == s.state.RunID && r.Status == StatusSuccess
+}
+
+// Get returns a copy of the state of repo
+func (s *Store) Get(repo string) (Repo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.state.Repos[repo]
+	if !ok {
+		return Repo{}, false
+	}
+	return *r, true
+}
+
+// StartRepo records that repo is being mirrored
+func (s *Store) StartRepo(repo string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(repo)
+	r.RunID = s.state.RunID
+	r.Status = StatusRunning
+	r.Error = ""
+	r.StartedAt = time.Now()
+	r.FinishedAt = time.Time{}
+	return s.save()
+}
+
+// FinishRepo records the outcome of mirroring repo
+func (s *Store) FinishRepo(repo string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.repo(repo)
+	r.FinishedAt = time.Now()
+	if err != nil {
+		r.Status = StatusFailed
+		r.Error = redact.String(err.Error())
+	} else {
+		r.Status = StatusSuccess
+		r.Error = ""
+		r.LastSuccessAt = r.FinishedAt
+	}
+	return s.save()
+}
+
+// SetRefs records the refs mirrored for repo
+func (s *Store) SetRefs(repo string, refs map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.repo(repo).Refs = refs
+	return s.save()
+}
+
+func (s *Store) repo(repo string) *Repo {
+	r, ok := s.state.Repos[repo]
+	if !ok {
+		r = &Repo{}
+		s.state.Repos[repo] = r
+	}
+	return r
+}
+
+// save writes the state atomically, the caller must hold mu
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
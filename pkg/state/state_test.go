@@ -0,0 +1,72 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed, err := s.BeginRun("github", "gitlab", true); err != nil || resumed {
+		t.Fatalf("BeginRun() on empty state = %v, %v, want false, nil", resumed, err)
+	}
+	s.StartRepo("group/api")
+	s.SetRefs("group/api", map[string]string{"refs/heads/main": "abc"})
+	s.FinishRepo("group/api", nil)
+	s.StartRepo("group/web")
+	s.FinishRepo("group/web", errors.New("push failed"))
+	s.StartRepo("group/docs")
+	// The run is interrupted here, EndRun is never called
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed, err := s.BeginRun("github", "gitlab", true); err != nil || !resumed {
+		t.Fatalf("BeginRun() on interrupted run = %v, %v, want true, nil", resumed, err)
+	}
+	if !s.Succeeded("group/api") {
+		t.Error("group/api succeeded in the interrupted run")
+	}
+	if s.Succeeded("group/web") || s.Succeeded("group/docs") {
+		t.Error("failed and unfinished repos must not count as succeeded")
+	}
+	if r, _ := s.Get("group/api"); r.Refs["refs/heads/main"] != "abc" || r.LastSuccessAt.IsZero() {
+		t.Errorf("unexpected repo state: %+v", r)
+	}
+	if err := s.EndRun(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// A completed run is never resumed, refs are kept for the next run
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed, _ := s.BeginRun("github", "gitlab", true); resumed {
+		t.Error("completed run must not be resumed")
+	}
+	if s.Succeeded("group/api") {
+		t.Error("success of a previous run must not carry over")
+	}
+	if r, _ := s.Get("group/api"); r.Refs["refs/heads/main"] != "abc" {
+		t.Errorf("refs lost across runs: %+v", r)
+	}
+}
+
+func TestResumeOtherJob(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.BeginRun("github", "gitlab", false)
+	if resumed, _ := s.BeginRun("github", "gitee", true); resumed {
+		t.Error("run with another target must not be resumed")
+	}
+}
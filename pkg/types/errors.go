@@ -0,0 +1,20 @@
+package types
+
+// StatusError is returned by providers when an API responds with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+// NewStatusError wraps err with the HTTP status code of the response that caused it
+func NewStatusError(statusCode int, err error) error {
+	return &StatusError{StatusCode: statusCode, Err: err}
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
@@ -1,5 +1,12 @@
 package types
 
+import (
+	"context"
+	"net/http"
+
+	"github.com/k8scat/mirror-git-go/pkg/git"
+)
+
 type Git interface {
 	// Name returns the name of the Git service
 	Name() string
@@ -9,13 +16,13 @@ type TargetGit interface {
 	Git
 
 	// IsRepoExist checks if a repository exists
-	IsRepoExist(repoName string) (bool, error)
+	IsRepoExist(ctx context.Context, repoName string) (bool, error)
 
 	// CreateRepo creates a new repository
-	CreateRepo(name string, desc string, private bool) error
+	CreateRepo(ctx context.Context, name string, desc string, private bool) error
 
 	// GetTargetRepoAddr returns the target repository address
-	GetTargetRepoAddr(path string) string
+	GetTargetRepoAddr(ctx context.Context, path string) string
 }
 
 type SourceGit interface {
@@ -25,5 +32,69 @@ type SourceGit interface {
 	GetSourceRepoAddr(pathWithNamespace string) string
 
 	// ListRepos lists all repositories
-	ListRepos() ([]Repo, error)
+	ListRepos(ctx context.Context) ([]Repo, error)
+}
+
+// NestedTargetGit is implemented by targets that accept repository paths
+// containing namespaces (e.g. group/sub/repo) and recreate the hierarchy
+type NestedTargetGit interface {
+	TargetGit
+
+	// SupportsNestedPaths reports whether nested repository paths can be created
+	SupportsNestedPaths() bool
+}
+
+// NormalizingTargetGit is implemented by targets that store repository paths in a normalized
+// form, so that different paths can name the same repository
+type NormalizingTargetGit interface {
+	TargetGit
+
+	// NormalizePath returns the path the target stores a repository path as
+	NormalizePath(path string) string
+}
+
+// HTTPGit is implemented by providers that call an HTTP API, so that the
+// requests of all workers can share one transport
+type HTTPGit interface {
+	Git
+
+	// SetTransport sets the transport of the API requests. The transport times out the requests,
+	// a client timeout would also cover the time it waits for a rate limit to reset.
+	SetTransport(rt http.RoundTripper)
+}
+
+// SSHGit is implemented by providers that can clone and push over SSH
+type SSHGit interface {
+	Git
+
+	// SetSSH makes the repository addresses use SSH, nil switches back to HTTP(S)
+	SetSSH(ssh *git.SSH)
+
+	// SSHConfig returns the SSH settings, nil when HTTP(S) is used
+	SSHConfig() *git.SSH
+}
+
+// CredentialGit is implemented by providers that authenticate with a username and access token,
+// so that the credentials can be resolved from other sources than the environment
+type CredentialGit interface {
+	Git
+
+	// Credentials returns the username and access token
+	Credentials() (username, token string)
+
+	// SetCredentials replaces the username and access token
+	SetCredentials(username, token string)
+
+	// CredentialURL returns the URL git credential helpers are asked for the credentials of
+	CredentialURL() string
+}
+
+// PrePushGit is implemented by targets that must change an existing repository
+// before a mirror push can overwrite it
+type PrePushGit interface {
+	TargetGit
+
+	// PreparePush readies an existing repository for a mirror push, e.g. by lifting branch protection.
+	// It is not called by dry runs, which must not change the target.
+	PreparePush(ctx context.Context, repoName string) error
 }
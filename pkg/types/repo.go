@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 type Repo interface {
 	// GetPath returns the repository path (name)
 	GetPath() string
@@ -14,13 +16,35 @@ type Repo interface {
 	GetPrivate() bool
 }
 
+// RepoMetadata is implemented by repositories that carry the details reported by
+// the provider. Zero values mean that the provider does not report the detail.
+type RepoMetadata interface {
+	// IsFork returns whether the repository is a fork
+	IsFork() bool
+
+	// IsArchived returns whether the repository is archived
+	IsArchived() bool
+
+	// GetSize returns the size of the repository in bytes
+	GetSize() int64
+
+	// GetPushedAt returns the time of the last push to the repository
+	GetPushedAt() time.Time
+}
+
 type RepoImpl struct {
 	Path              string
 	PathWithNamespace string
 	Desc              string
 	Private           bool
+	Fork              bool
+	Archived          bool
+	Size              int64
+	PushedAt          time.Time
 }
 
+var _ RepoMetadata = &RepoImpl{}
+
 func NewRepo(path, pathWithNamespace, desc string, private bool) Repo {
 	return &RepoImpl{
 		Path:              path,
@@ -45,3 +69,19 @@ func (r *RepoImpl) GetDesc() string {
 func (r *RepoImpl) GetPrivate() bool {
 	return r.Private
 }
+
+func (r *RepoImpl) IsFork() bool {
+	return r.Fork
+}
+
+func (r *RepoImpl) IsArchived() bool {
+	return r.Archived
+}
+
+func (r *RepoImpl) GetSize() int64 {
+	return r.Size
+}
+
+func (r *RepoImpl) GetPushedAt() time.Time {
+	return r.PushedAt
+}
@@ -0,0 +1,165 @@
+package urllist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/k8scat/mirror-git-go/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+var _ types.SourceGit = &URLList{}
+
+// URLList is a source that reads repositories from a file instead of a hosting API.
+//
+// Files ending in .json, .yaml or .yml contain a list of entries, each either
+// a plain URL string or an object with url, name, description and private fields.
+// Any other file is read as one URL per line, blank lines and lines starting
+// with # are ignored.
+type URLList struct {
+	File string
+
+	mu   sync.Mutex
+	urls map[string]string
+}
+
+// Entry is a single repository in a URL list file
+type Entry struct {
+	URL         string `json:"url" yaml:"url"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Private     bool   `json:"private" yaml:"private"`
+}
+
+// UnmarshalJSON accepts either a plain URL string or an object.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &e.URL)
+	}
+	type entry Entry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// UnmarshalYAML accepts either a plain URL string or a mapping.
+func (e *Entry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.URL)
+	}
+	type entry Entry
+	return node.Decode((*entry)(e))
+}
+
+func NewURLList(file string) *URLList {
+	return &URLList{File: file}
+}
+
+func NewURLListFromEnv() *URLList {
+	return NewURLList(os.Getenv("URLLIST_FILE"))
+}
+
+func (l *URLList) Name() string {
+	return "urllist"
+}
+
+// ReadEntries parses the list file
+func (l *URLList) ReadEntries() ([]Entry, error) {
+	data, err := os.ReadFile(l.File)
+	if err != nil {
+		return nil, fmt.Errorf("read url list failed: %w", err)
+	}
+
+	entries := make([]Entry, 0)
+	switch strings.ToLower(filepath.Ext(l.File)) {
+	case ".json":
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("parse json url list failed: %w", err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("parse yaml url list failed: %w", err)
+		}
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			entries = append(entries, Entry{URL: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("read url list failed: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// ListRepos implements types.SourceGit.
+func (l *URLList) ListRepos(ctx context.Context) ([]types.Repo, error) {
+	entries, err := l.ReadEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]string, len(entries))
+	repos := make([]types.Repo, 0, len(entries))
+	for i, e := range entries {
+		if e.URL == "" {
+			return nil, fmt.Errorf("entry %d: url is required", i+1)
+		}
+		pathWithNamespace := RepoPath(e.URL)
+		if pathWithNamespace == "" {
+			return nil, fmt.Errorf("entry %d: cannot derive repository path from url: %s", i+1, e.URL)
+		}
+		if e.Name != "" {
+			if dir := path.Dir(pathWithNamespace); dir != "." {
+				pathWithNamespace = dir + "/" + e.Name
+			} else {
+				pathWithNamespace = e.Name
+			}
+		}
+		if prev, ok := urls[pathWithNamespace]; ok {
+			return nil, fmt.Errorf("entry %d: %s resolves to %s, which is already used by %s", i+1, e.URL, pathWithNamespace, prev)
+		}
+		urls[pathWithNamespace] = e.URL
+		repos = append(repos, types.NewRepo(path.Base(pathWithNamespace), pathWithNamespace, e.Description, e.Private))
+	}
+
+	l.mu.Lock()
+	l.urls = urls
+	l.mu.Unlock()
+	return repos, nil
+}
+
+// GetSourceRepoAddr implements types.SourceGit.
+// It returns the URL from the list verbatim.
+func (l *URLList) GetSourceRepoAddr(pathWithNamespace string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.urls[pathWithNamespace]
+}
+
+// RepoPath derives the repository path with namespace from a git URL, e.g.
+// https://git.example.com/group/repo.git and git@example.com:group/repo.git
+// both yield group/repo.
+func RepoPath(rawURL string) string {
+	p := rawURL
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Host != "" {
+		p = u.Path
+	} else if i := strings.Index(rawURL, ":"); i > 0 && !strings.Contains(rawURL[:i], "/") {
+		// scp-like syntax: [user@]host:path
+		p = rawURL[i+1:]
+	}
+	p = strings.Trim(p, "/")
+	p = strings.TrimSuffix(p, ".git")
+	return strings.Trim(p, "/")
+}